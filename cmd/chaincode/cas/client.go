@@ -7,11 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package cas
 
 import (
-	"crypto"
-	"encoding/base64"
-
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
 )
 
 // New returns a new client for managing content
@@ -33,19 +31,25 @@ type Client struct {
 }
 
 // Write stores content to DCAS.
-// returns the SHA256 hash in base64url encoding which represents the address of the content
-func (mc *Client) Write(content []byte) (string, error) {
+// returns the multihash of the content (computed with the given multihash algorithm code)
+// in base64url encoding which represents the address of the content
+func (mc *Client) Write(content []byte, hashAlgorithm uint) (string, error) {
 
-	address := calculateAddress(content)
+	address, err := calculateAddress(content, hashAlgorithm)
+	if err != nil {
+		return "", err
+	}
 
-	if err := mc.stub.PutPrivateData(mc.collection, address, content); err != nil {
+	err = mc.stub.PutPrivateData(mc.collection, address, content)
+	if err != nil {
 		return "", errors.Wrap(err, "failed to store content")
 	}
 
 	return address, nil
 }
 
-// Read reads the content of the given address in DCAS.
+// Read reads the content of the given address in DCAS. Both multihash addresses and
+// legacy (plain SHA256) addresses are resolved since the address is used as is.
 // returns the content of the given address
 func (mc *Client) Read(address string) ([]byte, error) {
 
@@ -57,19 +61,13 @@ func (mc *Client) Read(address string) ([]byte, error) {
 	return payload, nil
 }
 
-// getHash will compute the hash for the supplied bytes using SHA256
-func getHash(bytes []byte) []byte {
-	h := crypto.SHA256.New()
-	// added no lint directive because there's no error from source code
-	// error cannot be produced, checked google source
-	h.Write(bytes) //nolint
-	return h.Sum(nil)
-}
+// calculateAddress returns the base64url encoded multihash of the content
+func calculateAddress(content []byte, hashAlgorithm uint) (string, error) {
 
-func calculateAddress(content []byte) string {
-	hash := getHash(content)
-	buf := make([]byte, base64.URLEncoding.EncodedLen(len(hash)))
-	base64.URLEncoding.Encode(buf, hash)
+	hash, err := docutil.ComputeMultihash(hashAlgorithm, content)
+	if err != nil {
+		return "", errors.Wrapf(err, "hash algorithm [%d] not supported", hashAlgorithm)
+	}
 
-	return string(buf)
+	return docutil.EncodeToString(hash), nil
 }
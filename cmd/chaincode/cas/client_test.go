@@ -19,17 +19,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const collection = "diddoc"
+const (
+	collection = "diddoc"
+	sha256Code = 18
+)
 
 func TestWrite(t *testing.T) {
 
 	client := getClient()
 
 	content := getOperationBytes(getCreateOperation())
-	addr, err := client.Write(content)
+	addr, err := client.Write(content, sha256Code)
 	require.Nil(t, err)
 	require.NotNil(t, addr)
-	require.Equal(t, encodedSHA256Hash(content), addr)
+	require.Equal(t, encodedMultihash(content), addr)
 
 	payload, err := client.Read(addr)
 	require.Nil(t, err)
@@ -37,11 +40,21 @@ func TestWrite(t *testing.T) {
 	require.Equal(t, content, payload)
 
 	// test write same content
-	addr2, err := client.Write(content)
+	addr2, err := client.Write(content, sha256Code)
 	require.Nil(t, err)
 	require.Equal(t, addr, addr2)
 }
 
+func TestWrite_UnsupportedHashAlgorithm(t *testing.T) {
+
+	client := getClient()
+
+	address, err := client.Write(getOperationBytes(getCreateOperation()), 55)
+	require.NotNil(t, err)
+	require.Empty(t, address)
+	require.Contains(t, err.Error(), "hash algorithm [55] not supported")
+}
+
 func TestWrite_PutPrivateError(t *testing.T) {
 
 	testErr := errors.New("write error")
@@ -50,7 +63,7 @@ func TestWrite_PutPrivateError(t *testing.T) {
 	client := New(mockStub, collection)
 
 	content := getOperationBytes(getCreateOperation())
-	address, err := client.Write(content)
+	address, err := client.Write(content, sha256Code)
 	require.NotNil(t, err)
 	require.Empty(t, address)
 	require.Contains(t, err.Error(), testErr.Error())
@@ -61,7 +74,7 @@ func TestRead(t *testing.T) {
 	client := getClient()
 
 	content := getOperationBytes(getCreateOperation())
-	addr, err := client.Write(content)
+	addr, err := client.Write(content, sha256Code)
 	require.Nil(t, err)
 	require.NotNil(t, addr)
 
@@ -75,6 +88,20 @@ func TestRead(t *testing.T) {
 	require.Nil(t, read)
 }
 
+func TestRead_LegacyAddress(t *testing.T) {
+
+	client := getClient()
+
+	// content written before multihash addressing was introduced
+	content := getOperationBytes(getCreateOperation())
+	legacyAddr := encodedSHA256Hash(content)
+	require.Nil(t, client.stub.PutPrivateData(collection, legacyAddr, content))
+
+	read, err := client.Read(legacyAddr)
+	require.Nil(t, err)
+	require.Equal(t, content, read)
+}
+
 func TestRead_GetPrivateError(t *testing.T) {
 
 	testErr := errors.New("read error")
@@ -97,6 +124,18 @@ func encodedSHA256Hash(bytes []byte) string {
 	return base64.URLEncoding.EncodeToString(h.Sum(nil))
 }
 
+func encodedMultihash(bytes []byte) string {
+	h := crypto.SHA256.New()
+	if _, err := h.Write(bytes); err != nil {
+		panic(err)
+	}
+
+	// multihash prefix: sha2-256 code followed by the digest length
+	mh := append([]byte{sha256Code, byte(h.Size())}, h.Sum(nil)...)
+
+	return base64.URLEncoding.EncodeToString(mh)
+}
+
 func newMockStub() *mocks.MockStub {
 	return mocks.NewMockStub("mockcc", &mockCC{})
 }
@@ -17,6 +17,7 @@ import (
 	"strings"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/pkg/errors"
 
@@ -45,6 +46,10 @@ type MockStub struct {
 	Invokables map[string]*MockStub
 	// Transient map
 	Transient map[string][]byte
+	// Creator is the serialized identity of the transaction creator
+	Creator []byte
+	// Event is the chaincode event set by the last transaction
+	Event *pb.ChaincodeEvent
 
 	//PvtState contains private state
 	PvtState map[string]stateMap
@@ -55,6 +60,7 @@ type MockStub struct {
 	// Errors used for testing
 	GetPrivateErr error
 	PutPrivateErr error
+	QueryErr      error
 }
 
 // GetTransient returns transient map
@@ -62,6 +68,21 @@ func (stub *MockStub) GetTransient() (map[string][]byte, error) {
 	return stub.Transient, nil
 }
 
+// GetCreator returns the serialized identity of the transaction creator
+func (stub *MockStub) GetCreator() ([]byte, error) {
+	return stub.Creator, nil
+}
+
+// SetEvent records the chaincode event of the transaction
+func (stub *MockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be nil string")
+	}
+
+	stub.Event = &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
 //GetArgs returns args
 func (stub *MockStub) GetArgs() [][]byte {
 	return stub.args
@@ -100,6 +121,41 @@ func (stub *MockStub) DelState(key string) error {
 	return stub.delState("", key)
 }
 
+// GetStateByPartialCompositeKeyWithPagination returns a page of the keys (in lexical order) which match
+// the given partial composite key. The bookmark is the key of the first record of the page.
+func (stub *MockStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if stub.QueryErr != nil {
+		return nil, nil, stub.QueryErr
+	}
+
+	partialKey, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sm := stub.getStateMap("")
+	it := &kvIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+
+	for elem := stub.getKeys("").Front(); elem != nil; elem = elem.Next() {
+		key := elem.Value.(string)
+		if !strings.HasPrefix(key, partialKey) || key < bookmark {
+			continue
+		}
+
+		if int32(len(it.kvs)) == pageSize {
+			metadata.Bookmark = key
+			break
+		}
+
+		it.kvs = append(it.kvs, &queryresult.KV{Key: key, Value: sm[key]})
+	}
+
+	metadata.FetchedRecordsCount = int32(len(it.kvs))
+
+	return it, metadata, nil
+}
+
 func (stub *MockStub) getStateMap(collection string) stateMap {
 	sm, ok := stub.PvtState[collection]
 	if !ok {
@@ -235,8 +291,35 @@ func (stub *MockStub) MockInit(uuid string, args [][]byte) pb.Response {
 //MockInvoke invokes chaincode
 func (stub *MockStub) MockInvoke(uuid string, args [][]byte) pb.Response {
 	stub.args = args
+	stub.Event = nil
 	stub.MockTransactionStart(uuid)
 	res := stub.cc.Invoke(stub)
 	stub.MockTransactionEnd(uuid)
 	return res
 }
+
+// kvIterator iterates over the results of a mock query
+type kvIterator struct {
+	kvs []*queryresult.KV
+}
+
+// HasNext returns true if there are more results
+func (it *kvIterator) HasNext() bool {
+	return len(it.kvs) > 0
+}
+
+// Next returns the next result
+func (it *kvIterator) Next() (*queryresult.KV, error) {
+	if len(it.kvs) == 0 {
+		return nil, errors.New("no more results")
+	}
+
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+// Close closes the iterator
+func (it *kvIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+)
+
+const (
+	// anchorObjectType is the object type of the composite keys under which anchors are recorded by transaction ID
+	anchorObjectType = "anchor"
+
+	defaultAnchorsPageSize = 100
+	maxAnchorsPageSize     = 1000
+)
+
+// anchorRecord is a Sidetree transaction recorded under a composite key which encodes its transaction ID
+type anchorRecord struct {
+	TxID            string `json:"txID"`
+	AnchorAddress   string `json:"anchorAddress"`
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// anchorPage is a page of anchors returned by getAnchors
+type anchorPage struct {
+	Anchors  []anchorRecord `json:"anchors"`
+	Bookmark string         `json:"bookmark,omitempty"`
+}
+
+// getAnchors returns a page of anchors ordered by transaction ID. The first (optional) argument is the page size
+// and the second (optional) argument is the bookmark returned with the previous page. The ledger order of the
+// anchors is given by the number of their block and their position within the block (see the observer).
+func (t *SidetreeTxnCC) getAnchors(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
+	txID := stub.GetTxID()
+
+	pageSize, err := getPageSize(args)
+	if err != nil {
+		logger.Debugf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	bookmark := ""
+	if len(args) > 1 {
+		bookmark = string(args[1])
+	}
+
+	page, err := queryAnchors(stub, pageSize, bookmark)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to query anchors: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	pageBytes, err := json.Marshal(page)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to marshal anchors: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	return shim.Success(pageBytes)
+}
+
+func queryAnchors(stub shim.ChaincodeStubInterface, pageSize int32, bookmark string) (*anchorPage, error) {
+
+	it, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(anchorObjectType, nil, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if e := it.Close(); e != nil {
+			logger.Warningf("failed to close anchors iterator: %s", e.Error())
+		}
+	}()
+
+	page := &anchorPage{Anchors: []anchorRecord{}}
+	for it.HasNext() {
+		kv, e := it.Next()
+		if e != nil {
+			return nil, e
+		}
+
+		var record anchorRecord
+		e = json.Unmarshal(kv.Value, &record)
+		if e != nil {
+			return nil, errors.Wrapf(e, "invalid anchor record [%s]", kv.Key)
+		}
+
+		page.Anchors = append(page.Anchors, record)
+	}
+
+	if metadata != nil {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
+
+// putAnchorRecord records the anchor under a composite key which encodes the transaction ID. The key is
+// unique to the transaction, so concurrent anchors do not conflict.
+func putAnchorRecord(stub shim.ChaincodeStubInterface, anchorAddr, protocolVersion string) error {
+
+	key, err := stub.CreateCompositeKey(anchorObjectType, []string{stub.GetTxID()})
+	if err != nil {
+		return errors.Wrap(err, "failed to create anchor key")
+	}
+
+	recordBytes, err := json.Marshal(&anchorRecord{
+		TxID:            stub.GetTxID(),
+		AnchorAddress:   anchorAddr,
+		ProtocolVersion: protocolVersion,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal anchor record")
+	}
+
+	return stub.PutState(key, recordBytes)
+}
+
+// getPageSize returns the page size passed in the first argument (or the default page size)
+func getPageSize(args [][]byte) (int32, error) {
+
+	if len(args) < 1 || len(args[0]) == 0 {
+		return defaultAnchorsPageSize, nil
+	}
+
+	pageSize, err := strconv.ParseInt(string(args[0]), 10, 32)
+	if err != nil || pageSize < 1 || pageSize > maxAnchorsPageSize {
+		return 0, errors.Errorf("invalid page size [%s]: must be between 1 and %d", args[0], maxAnchorsPageSize)
+	}
+
+	return int32(pageSize), nil
+}
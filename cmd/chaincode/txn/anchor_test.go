@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-fabric/cmd/chaincode/mocks"
+)
+
+func TestGetAnchors(t *testing.T) {
+
+	stub := prepareStub()
+
+	page := queryAnchorPage(t, stub, [][]byte{[]byte(getAnchors)})
+	require.Empty(t, page.Anchors)
+	require.Empty(t, page.Bookmark)
+
+	// the anchors are ordered by transaction ID
+	txIDs := []string{"tx2", "tx0", "tx1"}
+	addresses := make(map[string]string)
+	for i, txID := range txIDs {
+		addresses[txID] = anchorTestBatch(t, stub, txID, i)
+	}
+
+	page = queryAnchorPage(t, stub, [][]byte{[]byte(getAnchors), []byte("2")})
+	require.Len(t, page.Anchors, 2)
+	require.NotEmpty(t, page.Bookmark)
+
+	for i, a := range page.Anchors {
+		txID := fmt.Sprintf("tx%d", i)
+		require.Equal(t, txID, a.TxID)
+		require.Equal(t, addresses[txID], a.AnchorAddress)
+		require.Equal(t, testProtocolVersion, a.ProtocolVersion)
+	}
+
+	page = queryAnchorPage(t, stub, [][]byte{[]byte(getAnchors), []byte("2"), []byte(page.Bookmark)})
+	require.Len(t, page.Anchors, 1)
+	require.Empty(t, page.Bookmark)
+	require.Equal(t, "tx2", page.Anchors[0].TxID)
+	require.Equal(t, addresses["tx2"], page.Anchors[0].AnchorAddress)
+
+	// default page size
+	page = queryAnchorPage(t, stub, [][]byte{[]byte(getAnchors)})
+	require.Len(t, page.Anchors, 3)
+	require.Empty(t, page.Bookmark)
+}
+
+func TestGetAnchors_InvalidPageSize(t *testing.T) {
+
+	stub := prepareStub()
+
+	for _, pageSize := range []string{"0", "-1", "1001", "abc"} {
+		payload, err := invoke(stub, [][]byte{[]byte(getAnchors), []byte(pageSize)})
+		require.NotNil(t, err)
+		require.Nil(t, payload)
+		require.Contains(t, err.Error(), fmt.Sprintf("invalid page size [%s]", pageSize))
+	}
+}
+
+func TestGetAnchors_QueryError(t *testing.T) {
+
+	stub := prepareStub()
+	stub.QueryErr = fmt.Errorf("query error")
+
+	payload, err := invoke(stub, [][]byte{[]byte(getAnchors)})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "failed to query anchors: query error")
+}
+
+func TestAnchorBatch_Concurrent(t *testing.T) {
+
+	stub := prepareStub()
+
+	// anchors which are endorsed concurrently do not write any common key
+	keys := make(map[string]string)
+	for _, txID := range []string{"tx1", "tx2"} {
+		stub.MockTransactionStart(txID)
+		require.Nil(t, putAnchorRecord(stub, "addr", testProtocolVersion))
+		stub.MockTransactionEnd(txID)
+
+		key, err := stub.CreateCompositeKey(anchorObjectType, []string{txID})
+		require.Nil(t, err)
+		keys[txID] = key
+	}
+
+	require.NotEqual(t, keys["tx1"], keys["tx2"])
+
+	page := queryAnchorPage(t, stub, [][]byte{[]byte(getAnchors)})
+	require.Len(t, page.Anchors, 2)
+	require.Equal(t, "tx1", page.Anchors[0].TxID)
+	require.Equal(t, "tx2", page.Anchors[1].TxID)
+}
+
+// anchorTestBatch anchors a batch file with the given number of operations in a transaction with the given ID
+// and returns the anchor file address
+func anchorTestBatch(t *testing.T, stub *mocks.MockStub, txID string, operationCount int) string {
+	ops := make([]string, operationCount)
+	for i := range ops {
+		ops[i] = fmt.Sprintf("op%d", i)
+	}
+
+	batch, err := json.Marshal(&struct {
+		Operations []string `json:"operations"`
+	}{Operations: ops})
+	require.Nil(t, err)
+
+	anchor := []byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, encodedMultihash(batch)))
+
+	stub.Transient = batchFiles(batch, anchor)
+	defer func() { stub.Transient = make(map[string][]byte) }()
+
+	res := stub.MockInvoke(txID, anchorBatchArgs())
+	require.Equal(t, int32(shim.OK), res.Status, res.Message)
+
+	return encodedMultihash(anchor)
+}
+
+func queryAnchorPage(t *testing.T, stub *mocks.MockStub, args [][]byte) *anchorPage {
+	payload, err := invoke(stub, args)
+	require.Nil(t, err)
+
+	page := &anchorPage{}
+	require.Nil(t, json.Unmarshal(payload, page))
+
+	return page
+}
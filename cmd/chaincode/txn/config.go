@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/pkg/errors"
+)
+
+const (
+	// configKey is the state key under which the chaincode configuration is stored
+	configKey = "config"
+
+	// defaultCollection is the name of the private data collection for storing content if none is configured
+	defaultCollection = "dcas"
+	// defaultAnchorPrefix is the key prefix of anchor addresses if none is configured
+	defaultAnchorPrefix = "sidetreetxn_"
+
+	// invalidNameChars are characters which may not be used in the collection name or anchor prefix
+	// (white space and the composite key delimiter)
+	invalidNameChars = " \t\n\x00"
+)
+
+// ccConfig holds the chaincode configuration which is supplied in the Init arguments
+type ccConfig struct {
+	// AllowArgsContent allows clients to pass content in the proposal arguments instead
+	// of the transient map. This is only intended for clients that do not support transient data
+	// since content passed in the arguments is included in the ordered transaction.
+	AllowArgsContent bool `json:"allowArgsContent"`
+
+	// AdminMSPs contains the IDs of the MSPs whose administrators may add protocol versions
+	AdminMSPs []string `json:"adminMSPs"`
+
+	// Collection is the name of the private data collection for storing content
+	Collection string `json:"collection"`
+
+	// AnchorPrefix is the key prefix under which anchor addresses are recorded. Nodes which
+	// observe the ledger must be configured with the same prefix.
+	AnchorPrefix string `json:"anchorPrefix"`
+}
+
+// initConfig stores the configuration passed in the Init arguments (if any).
+// The first argument is the function name and the second argument is the JSON configuration.
+func initConfig(stub shim.ChaincodeStubInterface) error {
+
+	args := stub.GetArgs()
+	if len(args) < 2 || len(args[1]) == 0 {
+		// keep the existing configuration
+		return nil
+	}
+
+	cfg := &ccConfig{}
+	if err := json.Unmarshal(args[1], cfg); err != nil {
+		return errors.Wrap(err, "invalid chaincode configuration")
+	}
+
+	if err := cfg.validate(); err != nil {
+		return errors.Wrap(err, "invalid chaincode configuration")
+	}
+
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal chaincode configuration")
+	}
+
+	return stub.PutState(configKey, cfgBytes)
+}
+
+// validate checks that the names in the configuration can be used as a collection name and key prefix
+func (cfg *ccConfig) validate() error {
+
+	if strings.ContainsAny(cfg.Collection, invalidNameChars) {
+		return errors.Errorf("invalid collection name [%s]", cfg.Collection)
+	}
+
+	if strings.ContainsAny(cfg.AnchorPrefix, invalidNameChars) {
+		return errors.Errorf("invalid anchor prefix [%s]", cfg.AnchorPrefix)
+	}
+
+	if cfg.AnchorPrefix == "" {
+		return nil
+	}
+
+	// the anchor prefix must not cover the other keys of the chaincode
+	for _, key := range []string{configKey, protocolKey} {
+		if strings.HasPrefix(key, cfg.AnchorPrefix) {
+			return errors.Errorf("anchor prefix [%s] conflicts with key [%s]", cfg.AnchorPrefix, key)
+		}
+	}
+
+	return nil
+}
+
+// getConfig returns the chaincode configuration or the default configuration if none was supplied.
+// Names which are not configured are set to their defaults.
+func getConfig(stub shim.ChaincodeStubInterface) (*ccConfig, error) {
+
+	cfgBytes, err := stub.GetState(configKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read chaincode configuration")
+	}
+
+	cfg := &ccConfig{}
+	if len(cfgBytes) > 0 {
+		err = json.Unmarshal(cfgBytes, cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid chaincode configuration")
+		}
+	}
+
+	if cfg.Collection == "" {
+		cfg.Collection = defaultCollection
+	}
+
+	if cfg.AnchorPrefix == "" {
+		cfg.AnchorPrefix = defaultAnchorPrefix
+	}
+
+	return cfg, nil
+}
+
+// getContent returns the content for the given transient key. If the transient map
+// does not contain the key and args content is allowed then the content at the given
+// argument index is returned.
+func getContent(stub shim.ChaincodeStubInterface, cfg *ccConfig, key string, args [][]byte, index int) ([]byte, error) {
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get transient map")
+	}
+
+	if content, ok := transient[key]; ok {
+		return content, nil
+	}
+
+	if cfg.AllowArgsContent && len(args) > index {
+		return args[index], nil
+	}
+
+	return nil, nil
+}
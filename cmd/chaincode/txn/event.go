@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/pkg/errors"
+)
+
+// anchorEventName is the name of the chaincode event which is set for every anchored Sidetree transaction
+const anchorEventName = "sidetreeAnchor"
+
+// anchorEvent is the payload of the chaincode event which is set for every anchored Sidetree transaction.
+// The protocol version and operation count are omitted if they are not known.
+type anchorEvent struct {
+	AnchorAddress   string `json:"anchorAddress"`
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+	OperationCount  int    `json:"operationCount,omitempty"`
+}
+
+// batchFile defines the part of the batch file schema needed to count the operations
+type batchFile struct {
+	Operations []json.RawMessage `json:"operations"`
+}
+
+// setAnchorEvent sets the anchor event for the given anchor address. The operation count is informational
+// only so it is omitted if it is 0 (i.e. not known).
+func setAnchorEvent(stub shim.ChaincodeStubInterface, anchorAddr, protocolVersion string, opCount int) error {
+
+	payload, err := json.Marshal(&anchorEvent{
+		AnchorAddress:   anchorAddr,
+		ProtocolVersion: protocolVersion,
+		OperationCount:  opCount,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal anchor event")
+	}
+
+	return stub.SetEvent(anchorEventName, payload)
+}
+
+// operationCount returns the number of operations in the given batch file or 0 if the batch file
+// cannot be parsed
+func operationCount(txID string, batchFileBytes []byte) int {
+	if len(batchFileBytes) == 0 {
+		return 0
+	}
+
+	bf := &batchFile{}
+	err := json.Unmarshal(batchFileBytes, bf)
+	if err != nil {
+		logger.Warningf("[txID %s] unable to count the operations in the batch file: %s", txID, err.Error())
+		return 0
+	}
+
+	return len(bf.Operations)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const (
+	// protocolKey is the state key under which the protocol version table is stored
+	protocolKey = "protocol"
+
+	// adminOU is the organizational unit of administrator certificates
+	adminOU = "admin"
+)
+
+// protocolVersions is the protocol version table (protocol parameters by version)
+type protocolVersions map[string]protocolVersion
+
+// protocolVersion holds the protocol parameters of a version and the ID of the transaction which added the
+// version. Since the chaincode cannot determine the block height, the nodes check that the version starts
+// after the block of this transaction (the first version is not checked since no rules applied before it).
+type protocolVersion struct {
+	protocol.Protocol
+	TxID string `json:"txID,omitempty"`
+}
+
+// getProtocol returns the protocol version table (JSON) which has the same format as the protocol file
+// (the parameters of each version also hold the ID of the transaction which added the version)
+func (t *SidetreeTxnCC) getProtocol(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
+	txID := stub.GetTxID()
+
+	versionsBytes, err := stub.GetState(protocolKey)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to read protocol versions: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	if len(versionsBytes) == 0 {
+		return pb.Response{
+			Status:  404,
+			Message: "protocol versions not found",
+		}
+	}
+
+	return shim.Success(versionsBytes)
+}
+
+// addProtocolVersion adds a protocol version to the protocol version table. The first argument is the version
+// and the second argument is the JSON protocol parameters. The version must start after all existing versions
+// and after the block of this transaction (which is checked by the nodes).
+// Only administrators of the MSPs configured in the chaincode configuration may add protocol versions.
+func (t *SidetreeTxnCC) addProtocolVersion(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
+	txID := stub.GetTxID()
+
+	if len(args) < 2 || len(args[0]) == 0 || len(args[1]) == 0 {
+		errMsg := "protocol version and parameters are required"
+		logger.Debugf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	err := checkAdmin(stub)
+	if err != nil {
+		errMsg := fmt.Sprintf("access denied: %s", err.Error())
+		logger.Warningf("[txID %s] %s", txID, errMsg)
+		return pb.Response{
+			Status:  403,
+			Message: errMsg,
+		}
+	}
+
+	version := string(args[0])
+
+	p := protocol.Protocol{}
+	err = json.Unmarshal(args[1], &p)
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid protocol parameters: %s", err.Error())
+		logger.Debugf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	versions, err := getProtocolVersions(stub)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	err = versions.add(version, p, txID)
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid protocol version [%s]: %s", version, err.Error())
+		logger.Debugf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	versionsBytes, err := json.Marshal(versions)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to marshal protocol versions: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	err = stub.PutState(protocolKey, versionsBytes)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to write protocol versions: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	logger.Infof("[txID %s] Added protocol version [%s] starting at blockchain time %d", txID, version, p.StartingBlockChainTime)
+
+	return shim.Success(nil)
+}
+
+func getProtocolVersions(stub shim.ChaincodeStubInterface) (protocolVersions, error) {
+
+	versionsBytes, err := stub.GetState(protocolKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read protocol versions")
+	}
+
+	versions := make(protocolVersions)
+	if len(versionsBytes) == 0 {
+		return versions, nil
+	}
+
+	err = json.Unmarshal(versionsBytes, &versions)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid protocol versions")
+	}
+
+	return versions, nil
+}
+
+// add validates the given protocol parameters and adds them to the table. The ID of the given transaction
+// is recorded unless the version is the first version.
+func (pv protocolVersions) add(version string, p protocol.Protocol, txID string) error {
+
+	if _, ok := pv[version]; ok {
+		return errors.New("version already exists")
+	}
+
+	if p.MaxOperationsPerBatch == 0 || p.MaxOperationByteSize == 0 {
+		return errors.New("maxOperationsPerBatch and maxOperationByteSize are required")
+	}
+
+	if _, err := docutil.ComputeMultihash(p.HashAlgorithmInMultiHashCode, nil); err != nil {
+		return errors.Errorf("hash algorithm [%d] not supported", p.HashAlgorithmInMultiHashCode)
+	}
+
+	// protocol versions may only be added for the future, i.e. after the latest version
+	for v, existing := range pv {
+		if p.StartingBlockChainTime <= existing.StartingBlockChainTime {
+			return errors.Errorf("starting blockchain time must be greater than the starting blockchain time of version [%s]", v)
+		}
+	}
+
+	if len(pv) == 0 {
+		txID = ""
+	}
+
+	pv[version] = protocolVersion{Protocol: p, TxID: txID}
+
+	return nil
+}
+
+// checkAdmin returns an error if the creator of the transaction is not an administrator
+// of one of the configured admin MSPs
+func checkAdmin(stub shim.ChaincodeStubInterface) error {
+
+	cfg, err := getConfig(stub)
+	if err != nil {
+		return err
+	}
+
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return errors.Wrap(err, "failed to get MSP ID of creator")
+	}
+
+	if !contains(cfg.AdminMSPs, mspID) {
+		return errors.Errorf("MSP [%s] is not an admin MSP", mspID)
+	}
+
+	isAdmin, err := cid.HasOUValue(stub, adminOU)
+	if err != nil {
+		return errors.Wrap(err, "failed to get OU of creator")
+	}
+
+	if !isAdmin {
+		return errors.Errorf("creator is not an administrator of MSP [%s]", mspID)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
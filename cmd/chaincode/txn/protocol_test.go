@@ -0,0 +1,160 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+
+	"github.com/trustbloc/sidetree-fabric/cmd/chaincode/mocks"
+)
+
+const (
+	adminMSP = "Org1MSP"
+
+	protocolV01 = `{"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100}`
+	protocolV10 = `{"startingBlockchainTime":500000,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":2000,"maxOperationsPerBatch":10000}`
+)
+
+func TestAddProtocolVersion(t *testing.T) {
+
+	stub := prepareProtocolStub(t)
+	stub.Creator = newIdentity(t, adminMSP, adminOU)
+
+	payload, err := invoke(stub, [][]byte{[]byte(getProtocol)})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "protocol versions not found")
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.Nil(t, err)
+
+	res := stub.MockInvoke("tx2", [][]byte{[]byte(addProtocolVersion), []byte("1.0"), []byte(protocolV10)})
+	require.Equal(t, int32(shim.OK), res.Status, res.Message)
+
+	payload, err = invoke(stub, [][]byte{[]byte(getProtocol)})
+	require.Nil(t, err)
+
+	// the table has the format of the protocol file
+	var versions map[string]protocol.Protocol
+	require.Nil(t, json.Unmarshal(payload, &versions))
+	require.Len(t, versions, 2)
+	require.Equal(t, uint(100), versions["0.1"].MaxOperationsPerBatch)
+	require.Equal(t, uint64(500000), versions["1.0"].StartingBlockChainTime)
+
+	// the transaction which added a version is recorded (except for the first version) so that the
+	// nodes can check that the version starts after the block of the transaction
+	var recorded protocolVersions
+	require.Nil(t, json.Unmarshal(payload, &recorded))
+	require.Empty(t, recorded["0.1"].TxID)
+	require.Equal(t, "tx2", recorded["1.0"].TxID)
+}
+
+func TestAddProtocolVersion_InvalidVersion(t *testing.T) {
+
+	stub := prepareProtocolStub(t)
+	stub.Creator = newIdentity(t, adminMSP, adminOU)
+
+	_, err := invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("1.0"), []byte(protocolV10)})
+	require.Nil(t, err)
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("1.0"), []byte(protocolV10)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "version already exists")
+
+	// versions may only be added after the latest version
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "must be greater than the starting blockchain time of version [1.0]")
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("2.0"), []byte(`{"startingBlockchainTime":600000,"hashAlgorithmInMultihashCode":18}`)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "maxOperationsPerBatch and maxOperationByteSize are required")
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("2.0"), []byte(`{"startingBlockchainTime":600000,"hashAlgorithmInMultihashCode":55,"maxOperationByteSize":500,"maxOperationsPerBatch":100}`)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "hash algorithm [55] not supported")
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("2.0"), []byte("{")})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid protocol parameters")
+
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("2.0")})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "protocol version and parameters are required")
+}
+
+func TestAddProtocolVersion_AccessDenied(t *testing.T) {
+
+	stub := prepareProtocolStub(t)
+
+	_, err := invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "access denied")
+
+	stub.Creator = newIdentity(t, "Org2MSP", adminOU)
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "MSP [Org2MSP] is not an admin MSP")
+
+	stub.Creator = newIdentity(t, adminMSP, "client")
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "creator is not an administrator of MSP [Org1MSP]")
+
+	// no admin MSPs configured
+	stub = prepareStub()
+	stub.Creator = newIdentity(t, adminMSP, adminOU)
+	_, err = invoke(stub, [][]byte{[]byte(addProtocolVersion), []byte("0.1"), []byte(protocolV01)})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "MSP [Org1MSP] is not an admin MSP")
+}
+
+func prepareProtocolStub(t *testing.T) *mocks.MockStub {
+	stub := prepareStub()
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte(`{"adminMSPs":["` + adminMSP + `"]}`)})
+
+	return stub
+}
+
+// newIdentity returns a serialized identity with a self-signed certificate with the given OU
+func newIdentity(t *testing.T, mspID, ou string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "user", OrganizationalUnit: []string{ou}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	identity, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}),
+	})
+	require.Nil(t, err)
+
+	return identity
+}
@@ -9,11 +9,14 @@ package main
 import (
 	"fmt"
 	"runtime/debug"
+	"strconv"
 
 	"github.com/trustbloc/sidetree-fabric/cmd/chaincode/cas"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
 )
 
 var logger = shim.NewLogger("sidetreetxncc")
@@ -24,11 +27,22 @@ const (
 	readContent  = "readContent"
 	writeAnchor  = "writeAnchor"
 	anchorBatch  = "anchorBatch"
+	getAnchors   = "getAnchors"
 	warmup       = "warmup"
-	// collection is the name of the private data collection for storing content
-	collection = "dcas"
-	// anchor address prefix
-	anchorAddrPrefix = "sidetreetxn_"
+
+	getProtocol        = "getProtocol"
+	addProtocolVersion = "addProtocolVersion"
+
+	// Transient map keys
+	contentKey         = "content"
+	batchFileKey       = "batchFile"
+	anchorFileKey      = "anchorFile"
+	hashAlgorithmKey   = "hashAlgorithm"
+	protocolVersionKey = "protocolVersion"
+
+	// legacyHashAlgorithm is the multihash code of SHA2-256 which is used to calculate the content
+	// addresses for clients which do not pass a hash algorithm
+	legacyHashAlgorithm = 18
 )
 
 // funcMap is a map of functions by function name
@@ -48,13 +62,23 @@ func new() shim.Chaincode {
 	cc.functions[readContent] = cc.read
 	cc.functions[writeAnchor] = cc.writeAnchor
 	cc.functions[anchorBatch] = cc.anchorBatch
+	cc.functions[getAnchors] = cc.getAnchors
 	cc.functions[warmup] = cc.warmup
+	cc.functions[getProtocol] = cc.getProtocol
+	cc.functions[addProtocolVersion] = cc.addProtocolVersion
 
 	return cc
 }
 
-// Init - nothing to do for now
+// Init stores the chaincode configuration (if provided)
 func (t *SidetreeTxnCC) Init(stub shim.ChaincodeStubInterface) pb.Response {
+
+	if err := initConfig(stub); err != nil {
+		errMsg := fmt.Sprintf("failed to initialize chaincode: %s", err.Error())
+		logger.Errorf("[txID %s] %s", stub.GetTxID(), errMsg)
+		return shim.Error(errMsg)
+	}
+
 	return shim.Success(nil)
 }
 
@@ -88,18 +112,38 @@ func (t *SidetreeTxnCC) Invoke(stub shim.ChaincodeStubInterface) (resp pb.Respon
 	return function(stub, args[1:])
 }
 
-// writeContent will write content using cas client
+// writeContent will write content using cas client. The content and the multihash algorithm code used
+// to calculate the content address (see getHashAlgorithm) are passed in the transient map.
 func (t *SidetreeTxnCC) write(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
 	txID := stub.GetTxID()
-	if len(args) < 1 || len(args[0]) == 0 {
-		err := "missing content"
-		logger.Debugf("[txID %s] %s", txID, err)
-		return shim.Error(err)
+
+	hashAlgorithm, err := getHashAlgorithm(stub)
+	if err != nil {
+		logger.Debugf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
 	}
 
-	client := cas.New(stub, collection)
+	cfg, err := getConfig(stub)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
 
-	address, err := client.Write(args[0])
+	content, err := getContent(stub, cfg, contentKey, args, 0)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	if len(content) == 0 {
+		errMsg := "missing content"
+		logger.Debugf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	client := cas.New(stub, cfg.Collection)
+
+	address, err := client.Write(content, hashAlgorithm)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to write content: %s", err.Error())
 		logger.Errorf("[txID %s] %s", txID, errMsg)
@@ -115,10 +159,19 @@ func (t *SidetreeTxnCC) read(stub shim.ChaincodeStubInterface, args [][]byte) pb
 	if len(args) < 1 || len(args[0]) == 0 {
 		errMsg := "missing content address"
 		logger.Debugf("[txID %s] %s", txID, errMsg)
-		return shim.Error(errMsg)
+		return pb.Response{
+			Status:  400,
+			Message: errMsg,
+		}
 	}
 
-	client := cas.New(stub, collection)
+	cfg, err := getConfig(stub)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	client := cas.New(stub, cfg.Collection)
 
 	address := string(args[0])
 	payload, err := client.Read(address)
@@ -139,20 +192,46 @@ func (t *SidetreeTxnCC) read(stub shim.ChaincodeStubInterface, args [][]byte) pb
 }
 
 // anchorBatch will store batch and anchor files using cas client and
-// record anchor file address on the ledger in one call
+// record anchor file address on the ledger in one call. The files, the multihash algorithm code used to
+// calculate the content addresses (see getHashAlgorithm) and the protocol version (which is required)
+// are passed in the transient map.
 func (t *SidetreeTxnCC) anchorBatch(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
 	txID := stub.GetTxID()
 
-	if len(args) < 2 || len(args[0]) == 0 || len(args[1]) == 0 {
+	hashAlgorithm, err := getHashAlgorithm(stub)
+	if err != nil {
+		logger.Debugf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	protocolVersion, err := getProtocolVersion(stub)
+	if err != nil {
+		logger.Debugf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	cfg, err := getConfig(stub)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	batchFile, anchorFile, err := getBatchFiles(stub, cfg, args)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	if len(batchFile) == 0 || len(anchorFile) == 0 {
 		errMsg := "batch and anchor files are required"
 		logger.Debugf("[txID %s] %s", txID, errMsg)
 		return shim.Error(errMsg)
 	}
 
-	client := cas.New(stub, collection)
+	client := cas.New(stub, cfg.Collection)
 
 	// write batch file
-	_, err := client.Write(args[0])
+	_, err = client.Write(batchFile, hashAlgorithm)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to write batch content: %s", err.Error())
 		logger.Errorf("[txID %s] %s", txID, errMsg)
@@ -160,25 +239,20 @@ func (t *SidetreeTxnCC) anchorBatch(stub shim.ChaincodeStubInterface, args [][]b
 	}
 
 	// write anchor file
-	anchorAddr, err := client.Write(args[1])
+	anchorAddr, err := client.Write(anchorFile, hashAlgorithm)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to write anchor content: %s", err.Error())
 		logger.Errorf("[txID %s] %s", txID, errMsg)
 		return shim.Error(errMsg)
 	}
 
-	// record anchor file address on the ledger (Sidetree Transaction)
-	err = stub.PutState(anchorAddrPrefix+anchorAddr, []byte(anchorAddr))
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to write anchor address: %s", err.Error())
-		logger.Errorf("[txID %s] %s", txID, errMsg)
-		return shim.Error(errMsg)
-	}
-
-	return shim.Success(nil)
+	return recordAnchor(stub, cfg, anchorAddr, protocolVersion, operationCount(txID, batchFile))
 }
 
-// writeAnchor will record anchor file address on the ledger
+// writeAnchor will record anchor file address on the ledger. The first argument is the anchor file address,
+// the optional second argument is the protocol version and the optional third argument is the number of
+// operations in the batch (which is only used for the anchor event). The anchor and batch files must have
+// been written to CAS.
 func (t *SidetreeTxnCC) writeAnchor(stub shim.ChaincodeStubInterface, args [][]byte) pb.Response {
 	txID := stub.GetTxID()
 	if len(args) < 1 || len(args[0]) == 0 {
@@ -189,17 +263,118 @@ func (t *SidetreeTxnCC) writeAnchor(stub shim.ChaincodeStubInterface, args [][]b
 
 	anchorAddr := string(args[0])
 
-	// record anchor file address on the ledger (Sidetree Transaction)
-	err := stub.PutState(anchorAddrPrefix+anchorAddr, []byte(anchorAddr))
+	protocolVersion := ""
+	if len(args) > 1 {
+		protocolVersion = string(args[1])
+	}
+
+	opCount := 0
+	if len(args) > 2 {
+		var err error
+		opCount, err = strconv.Atoi(string(args[2]))
+		if err != nil || opCount < 0 {
+			errMsg := fmt.Sprintf("invalid operation count [%s]", args[2])
+			logger.Debugf("[txID %s] %s", txID, errMsg)
+			return shim.Error(errMsg)
+		}
+	}
+
+	cfg, err := getConfig(stub)
+	if err != nil {
+		logger.Errorf("[txID %s] %s", txID, err.Error())
+		return shim.Error(err.Error())
+	}
+
+	return recordAnchor(stub, cfg, anchorAddr, protocolVersion, opCount)
+}
+
+// recordAnchor records the anchor file address on the ledger (Sidetree Transaction) under the configured
+// anchor prefix, records the anchor under its transaction number and sets the anchor event
+func recordAnchor(stub shim.ChaincodeStubInterface, cfg *ccConfig, anchorAddr, protocolVersion string, opCount int) pb.Response {
+	txID := stub.GetTxID()
+
+	err := stub.PutState(cfg.AnchorPrefix+anchorAddr, []byte(anchorAddr))
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to write anchor address: %s", err.Error())
 		logger.Errorf("[txID %s] %s", txID, errMsg)
 		return shim.Error(errMsg)
 	}
 
+	err = putAnchorRecord(stub, anchorAddr, protocolVersion)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to record anchor: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
+	err = setAnchorEvent(stub, anchorAddr, protocolVersion, opCount)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to set anchor event: %s", err.Error())
+		logger.Errorf("[txID %s] %s", txID, errMsg)
+		return shim.Error(errMsg)
+	}
+
 	return shim.Success(nil)
 }
 
+func getBatchFiles(stub shim.ChaincodeStubInterface, cfg *ccConfig, args [][]byte) ([]byte, []byte, error) {
+
+	batchFile, err := getContent(stub, cfg, batchFileKey, args, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	anchorFile, err := getContent(stub, cfg, anchorFileKey, args, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return batchFile, anchorFile, nil
+}
+
+// getHashAlgorithm returns the multihash algorithm code passed in the transient map. SHA2-256 is
+// returned for clients which predate the hash algorithm and do not pass it. An error is returned if the
+// code cannot be parsed or the algorithm is not supported.
+func getHashAlgorithm(stub shim.ChaincodeStubInterface) (uint, error) {
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get transient map")
+	}
+
+	value, ok := transient[hashAlgorithmKey]
+	if !ok {
+		return legacyHashAlgorithm, nil
+	}
+
+	hashAlgorithm, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid hash algorithm [%s]", value)
+	}
+
+	if _, err := docutil.ComputeMultihash(uint(hashAlgorithm), nil); err != nil {
+		return 0, errors.Errorf("hash algorithm [%d] not supported", hashAlgorithm)
+	}
+
+	return uint(hashAlgorithm), nil
+}
+
+// getProtocolVersion returns the protocol version passed in the transient map
+func getProtocolVersion(stub shim.ChaincodeStubInterface) (string, error) {
+
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get transient map")
+	}
+
+	protocolVersion := string(transient[protocolVersionKey])
+	if protocolVersion == "" {
+		return "", errors.New("missing protocol version")
+	}
+
+	return protocolVersion, nil
+}
+
 func (m funcMap) String() string {
 	str := ""
 	i := 0
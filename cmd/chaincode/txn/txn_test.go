@@ -9,6 +9,7 @@ package main
 import (
 	"crypto"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -18,6 +19,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+const (
+	// sha256Code is the multihash code of the SHA2-256 algorithm
+	sha256Code = "18"
+
+	testProtocolVersion = "1.0"
+)
+
 func TestInvoke(t *testing.T) {
 
 	stub := prepareStub()
@@ -33,22 +41,83 @@ func TestWrite(t *testing.T) {
 	stub := prepareStub()
 
 	testPayload := []byte("Test")
-	address, err := invoke(stub, [][]byte{[]byte(writeContent), testPayload})
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: testPayload}))
 	require.Nil(t, err)
-	require.Equal(t, encodedSHA256Hash(testPayload), string(address))
+	require.Equal(t, encodedMultihash(testPayload), string(address))
 
 	payload, err := invoke(stub, [][]byte{[]byte(readContent), []byte(address)})
 	require.Nil(t, err)
 	require.Equal(t, testPayload, payload)
 }
 
+func TestWrite_ArgsContent(t *testing.T) {
+
+	stub := prepareStub()
+
+	// content in args is ignored by default
+	testPayload := []byte("Test")
+	address, err := invoke(stub, [][]byte{[]byte(writeContent), testPayload})
+	require.NotNil(t, err)
+	require.Nil(t, address)
+	require.Contains(t, err.Error(), "missing content")
+
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte(`{"allowArgsContent":true}`)})
+
+	address, err = invokeWithTransient(stub, [][]byte{[]byte(writeContent), testPayload}, withHashAlgorithm(map[string][]byte{}))
+	require.Nil(t, err)
+	require.Equal(t, encodedMultihash(testPayload), string(address))
+
+	// transient content takes precedence over args content
+	transientPayload := []byte("Transient")
+	address, err = invokeWithTransient(stub, [][]byte{[]byte(writeContent), testPayload}, withHashAlgorithm(map[string][]byte{contentKey: transientPayload}))
+	require.Nil(t, err)
+	require.Equal(t, encodedMultihash(transientPayload), string(address))
+}
+
+func TestWrite_HashAlgorithm(t *testing.T) {
+
+	stub := prepareStub()
+
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, map[string][]byte{contentKey: []byte("Test"), hashAlgorithmKey: []byte("55")})
+	require.NotNil(t, err)
+	require.Nil(t, address)
+	require.Contains(t, err.Error(), "hash algorithm [55] not supported")
+
+	address, err = invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, map[string][]byte{contentKey: []byte("Test"), hashAlgorithmKey: []byte("sha256")})
+	require.NotNil(t, err)
+	require.Nil(t, address)
+	require.Contains(t, err.Error(), "invalid hash algorithm [sha256]")
+}
+
+func TestWrite_Legacy(t *testing.T) {
+
+	stub := prepareStub()
+	testPayload := []byte("Test")
+
+	// clients which do not pass a hash algorithm get SHA2-256 addresses
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, map[string][]byte{contentKey: testPayload})
+	require.Nil(t, err)
+	require.Equal(t, encodedMultihash(testPayload), string(address))
+
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte(`{"allowArgsContent":true}`)})
+
+	// the content is passed in the first argument
+	address, err = invoke(stub, [][]byte{[]byte(writeContent), testPayload})
+	require.Nil(t, err)
+	require.Equal(t, encodedMultihash(testPayload), string(address))
+
+	payload, err := invoke(stub, [][]byte{[]byte(readContent), address})
+	require.Nil(t, err)
+	require.Equal(t, testPayload, payload)
+}
+
 func TestWriteError(t *testing.T) {
 
 	testErr := fmt.Errorf("write error")
 	stub := prepareStub()
 	stub.PutPrivateErr = testErr
 
-	payload, err := invoke(stub, [][]byte{[]byte(writeContent), []byte("address")})
+	payload, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: []byte("content")}))
 	require.NotNil(t, err)
 	require.Nil(t, payload)
 	require.Contains(t, err.Error(), testErr.Error())
@@ -58,7 +127,12 @@ func TestWrite_MissingContent(t *testing.T) {
 
 	stub := prepareStub()
 
-	address, err := invoke(stub, [][]byte{[]byte(writeContent), []byte("")})
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{}))
+	require.NotNil(t, err)
+	require.Nil(t, address)
+	require.Contains(t, err.Error(), "missing content")
+
+	address, err = invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: []byte("")}))
 	require.NotNil(t, err)
 	require.Nil(t, address)
 	require.Contains(t, err.Error(), "missing content")
@@ -69,13 +143,13 @@ func TestRead(t *testing.T) {
 	stub := prepareStub()
 
 	testPayload := []byte("Test")
-	testPayloadAddress := encodedSHA256Hash(testPayload)
+	testPayloadAddress := encodedMultihash(testPayload)
 
 	payload, err := invoke(stub, [][]byte{[]byte(readContent), []byte(testPayloadAddress)})
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "not found")
 
-	address, err := invoke(stub, [][]byte{[]byte(writeContent), testPayload})
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: testPayload}))
 	require.Nil(t, err)
 	require.Equal(t, testPayloadAddress, string(address))
 
@@ -104,20 +178,27 @@ func TestRead_MissingAddress(t *testing.T) {
 	require.NotNil(t, err)
 	require.Nil(t, address)
 	require.Contains(t, err.Error(), "missing content address")
+
+	res := stub.MockInvoke("1", [][]byte{[]byte(readContent)})
+	require.Equal(t, int32(400), res.Status)
 }
 
 func TestWriteAnchor(t *testing.T) {
 
 	stub := prepareStub()
 
-	anchorAddress := []byte("Addr")
-	payload, err := invoke(stub, [][]byte{[]byte(writeAnchor), anchorAddress})
+	batch, anchor := newBatchFiles()
+	anchorAddress := writeFiles(t, stub, batch, anchor)
+
+	payload, err := invoke(stub, [][]byte{[]byte(writeAnchor), []byte(anchorAddress), []byte(testProtocolVersion), []byte("2")})
 	require.Nil(t, err)
 	require.Nil(t, payload)
 
-	result, err := stub.GetState(anchorAddrPrefix + string(anchorAddress))
+	result, err := stub.GetState(defaultAnchorPrefix + anchorAddress)
 	require.Nil(t, err)
-	require.Equal(t, anchorAddress, result)
+	require.Equal(t, anchorAddress, string(result))
+
+	checkAnchorEvent(t, stub, anchorAddress, 2)
 }
 
 func TestWriteAnchor_MissingAnchorAddress(t *testing.T) {
@@ -130,20 +211,161 @@ func TestWriteAnchor_MissingAnchorAddress(t *testing.T) {
 	require.Contains(t, err.Error(), "missing anchor file address")
 }
 
+func TestWriteAnchor_NoProtocolVersion(t *testing.T) {
+
+	stub := prepareStub()
+
+	batch, anchor := newBatchFiles()
+	anchorAddress := writeFiles(t, stub, batch, anchor)
+
+	payload, err := invoke(stub, [][]byte{[]byte(writeAnchor), []byte(anchorAddress)})
+	require.Nil(t, err)
+	require.Nil(t, payload)
+
+	result, err := stub.GetState(defaultAnchorPrefix + anchorAddress)
+	require.Nil(t, err)
+	require.Equal(t, anchorAddress, string(result))
+
+	event := getAnchorEvent(t, stub)
+	require.Equal(t, anchorAddress, event.AnchorAddress)
+	require.Empty(t, event.ProtocolVersion)
+	require.Zero(t, event.OperationCount)
+}
+
+func TestWriteAnchor_NoOperationCount(t *testing.T) {
+
+	stub := prepareStub()
+
+	// the anchor is recorded without reading its content and the event does not contain the operation count
+	payload, err := invoke(stub, [][]byte{[]byte(writeAnchor), []byte("Addr"), []byte(testProtocolVersion)})
+	require.Nil(t, err)
+	require.Nil(t, payload)
+	checkAnchorEvent(t, stub, "Addr", 0)
+
+	event := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(stub.Event.Payload, &event))
+	require.NotContains(t, event, "operationCount")
+
+	result, err := stub.GetState(defaultAnchorPrefix + "Addr")
+	require.Nil(t, err)
+	require.Equal(t, "Addr", string(result))
+}
+
+func TestWriteAnchor_InvalidOperationCount(t *testing.T) {
+
+	stub := prepareStub()
+
+	for _, count := range []string{"two", "-1"} {
+		payload, err := invoke(stub, [][]byte{[]byte(writeAnchor), []byte("Addr"), []byte(testProtocolVersion), []byte(count)})
+		require.NotNil(t, err)
+		require.Nil(t, payload)
+		require.Contains(t, err.Error(), "invalid operation count")
+	}
+}
+
 func TestAnchorBatch(t *testing.T) {
 
 	stub := prepareStub()
 
-	batch := []byte("Ops")
-	anchor := []byte("anchor")
-	payload, err := invoke(stub, [][]byte{[]byte(anchorBatch), batch, anchor})
+	batch, anchor := newBatchFiles()
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), batchFiles(batch, anchor))
+	require.Nil(t, err)
+	require.Nil(t, payload)
+
+	result, err := stub.GetState(defaultAnchorPrefix + encodedMultihash(anchor))
+	require.Nil(t, err)
+	require.Equal(t, string(result), encodedMultihash(anchor))
+
+	checkAnchorEvent(t, stub, encodedMultihash(anchor), 2)
+}
+
+func TestAnchorBatch_InvalidBatchFile(t *testing.T) {
+
+	stub := prepareStub()
+
+	_, anchor := newBatchFiles()
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), batchFiles([]byte("Ops"), anchor))
+	require.Nil(t, err)
+	require.Nil(t, payload)
+
+	checkAnchorEvent(t, stub, encodedMultihash(anchor), 0)
+
+	event := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(stub.Event.Payload, &event))
+	require.NotContains(t, event, "operationCount")
+}
+
+func TestAnchorBatch_HashAlgorithm(t *testing.T) {
+
+	stub := prepareStub()
+
+	transient := batchFiles(newBatchFiles())
+	transient[hashAlgorithmKey] = []byte("55")
+
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), transient)
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "hash algorithm [55] not supported")
+}
+
+func TestAnchorBatch_MissingProtocolVersion(t *testing.T) {
+
+	stub := prepareStub()
+
+	transient := batchFiles(newBatchFiles())
+	delete(transient, protocolVersionKey)
+
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), transient)
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "missing protocol version")
+}
+
+func TestAnchorBatch_ArgsContent(t *testing.T) {
+
+	stub := prepareStub()
+
+	batch, anchor := newBatchFiles()
+
+	// files in args are ignored by default
+	payload, err := invokeWithTransient(stub, append(anchorBatchArgs(), batch, anchor), withAnchorParams(map[string][]byte{}))
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "batch and anchor files are required")
+
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte(`{"allowArgsContent":true}`)})
+
+	payload, err = invokeWithTransient(stub, append(anchorBatchArgs(), batch, anchor), withAnchorParams(map[string][]byte{}))
+	require.Nil(t, err)
+	require.Nil(t, payload)
+
+	result, err := stub.GetState(defaultAnchorPrefix + encodedMultihash(anchor))
+	require.Nil(t, err)
+	require.Equal(t, string(result), encodedMultihash(anchor))
+}
+
+func TestAnchorBatch_Legacy(t *testing.T) {
+
+	stub := prepareStub()
+
+	// clients which do not pass a hash algorithm get SHA2-256 addresses
+	batch, anchor := newBatchFiles()
+	transient := batchFiles(batch, anchor)
+	delete(transient, hashAlgorithmKey)
+
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), transient)
 	require.Nil(t, err)
 	require.Nil(t, payload)
 
-	result, err := stub.GetState(anchorAddrPrefix + encodedSHA256Hash(anchor))
+	result, err := stub.GetState(defaultAnchorPrefix + encodedMultihash(anchor))
 	require.Nil(t, err)
-	require.Equal(t, string(result), encodedSHA256Hash(anchor))
+	require.Equal(t, encodedMultihash(anchor), string(result))
 
+	// the protocol version is required
+	payload, err = invokeWithTransient(stub, anchorBatchArgs(), map[string][]byte{batchFileKey: batch, anchorFileKey: anchor})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "missing protocol version")
 }
 
 func TestAnchorBatch_CASClientError(t *testing.T) {
@@ -151,7 +373,7 @@ func TestAnchorBatch_CASClientError(t *testing.T) {
 	stub := prepareStub()
 	stub.PutPrivateErr = fmt.Errorf("write error")
 
-	payload, err := invoke(stub, [][]byte{[]byte(anchorBatch), []byte("Ops"), []byte("anchor")})
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), batchFiles(newBatchFiles()))
 	require.NotNil(t, err)
 	require.Nil(t, payload)
 	require.Contains(t, err.Error(), "write error")
@@ -162,7 +384,8 @@ func TestAnchorBatch_PutStateError(t *testing.T) {
 	stub := prepareStub()
 	stub.MockStub.TxID = ""
 
-	res := stub.MockInvoke("", [][]byte{[]byte(anchorBatch), []byte("Ops"), []byte("anchor")})
+	stub.Transient = batchFiles(newBatchFiles())
+	res := stub.MockInvoke("", anchorBatchArgs())
 	require.NotEqual(t, res.Status, shim.OK)
 }
 
@@ -170,17 +393,83 @@ func TestAnchorBatch_MissingRequiredParameters(t *testing.T) {
 
 	stub := prepareStub()
 
-	payload, err := invoke(stub, [][]byte{[]byte(anchorBatch), []byte("")})
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), withAnchorParams(map[string][]byte{}))
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), "batch and anchor files are required")
+
+	payload, err = invokeWithTransient(stub, anchorBatchArgs(), withAnchorParams(map[string][]byte{batchFileKey: []byte("Ops")}))
 	require.NotNil(t, err)
 	require.Nil(t, payload)
 	require.Contains(t, err.Error(), "batch and anchor files are required")
 
-	payload, err = invoke(stub, [][]byte{[]byte(anchorBatch), []byte("Ops"), []byte("")})
+	payload, err = invokeWithTransient(stub, anchorBatchArgs(), batchFiles([]byte("Ops"), []byte("")))
 	require.NotNil(t, err)
 	require.Nil(t, payload)
 	require.Contains(t, err.Error(), "batch and anchor files are required")
 }
 
+func TestInitInvalidConfig(t *testing.T) {
+
+	stub := prepareStub()
+
+	res := stub.MockInit("1", [][]byte{[]byte("init"), []byte("{invalid")})
+	require.NotEqual(t, shim.OK, res.Status)
+	require.Contains(t, res.Message, "invalid chaincode configuration")
+
+	res = stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"collection":"my collection"}`)})
+	require.NotEqual(t, shim.OK, res.Status)
+	require.Contains(t, res.Message, "invalid collection name [my collection]")
+
+	res = stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"anchorPrefix":"st\u0000"}`)})
+	require.NotEqual(t, shim.OK, res.Status)
+	require.Contains(t, res.Message, "invalid anchor prefix")
+
+	res = stub.MockInit("1", [][]byte{[]byte("init"), []byte(`{"anchorPrefix":"proto"}`)})
+	require.NotEqual(t, shim.OK, res.Status)
+	require.Contains(t, res.Message, "anchor prefix [proto] conflicts with key [protocol]")
+}
+
+func TestConfiguredNames(t *testing.T) {
+
+	stub := prepareStub()
+
+	const collection = "dcas2"
+	const anchorPrefix = "sidetree2_"
+
+	checkInit(t, stub, [][]byte{[]byte("init"), []byte(`{"collection":"` + collection + `","anchorPrefix":"` + anchorPrefix + `"}`)})
+
+	batch, anchor := newBatchFiles()
+
+	payload, err := invokeWithTransient(stub, anchorBatchArgs(), batchFiles(batch, anchor))
+	require.Nil(t, err)
+	require.Nil(t, payload)
+
+	anchorAddress := encodedMultihash(anchor)
+
+	// content is stored in the configured collection
+	content, err := stub.GetPrivateData(collection, anchorAddress)
+	require.Nil(t, err)
+	require.Equal(t, anchor, content)
+
+	content, err = stub.GetPrivateData(defaultCollection, anchorAddress)
+	require.Nil(t, err)
+	require.Nil(t, content)
+
+	// the anchor is recorded under the configured prefix
+	result, err := stub.GetState(anchorPrefix + anchorAddress)
+	require.Nil(t, err)
+	require.Equal(t, anchorAddress, string(result))
+
+	result, err = stub.GetState(defaultAnchorPrefix + anchorAddress)
+	require.Nil(t, err)
+	require.Nil(t, result)
+
+	read, err := invoke(stub, [][]byte{[]byte(readContent), []byte(anchorAddress)})
+	require.Nil(t, err)
+	require.Equal(t, anchor, read)
+}
+
 func TestWarmup(t *testing.T) {
 
 	stub := prepareStub()
@@ -201,14 +490,17 @@ func TestHandlePanic(t *testing.T) {
 	require.Contains(t, err.Error(), "panic")
 }
 
-func encodedSHA256Hash(bytes []byte) string {
+func encodedMultihash(bytes []byte) string {
 
 	h := crypto.SHA256.New()
 	if _, err := h.Write(bytes); err != nil {
 		panic(err)
 	}
 
-	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+	// multihash prefix: sha2-256 code followed by the digest length
+	mh := append([]byte{0x12, byte(h.Size())}, h.Sum(nil)...)
+
+	return base64.URLEncoding.EncodeToString(mh)
 }
 
 func testInvalidFunctionName(t *testing.T, stub *mocks.MockStub) {
@@ -241,6 +533,69 @@ func checkInit(t *testing.T, stub *mocks.MockStub, args [][]byte) {
 	}
 }
 
+func anchorBatchArgs() [][]byte {
+	return [][]byte{[]byte(anchorBatch)}
+}
+
+// withHashAlgorithm adds the SHA2-256 hash algorithm to the given transient map
+func withHashAlgorithm(transient map[string][]byte) map[string][]byte {
+	transient[hashAlgorithmKey] = []byte(sha256Code)
+	return transient
+}
+
+// withAnchorParams adds the SHA2-256 hash algorithm and the test protocol version to the given transient map
+func withAnchorParams(transient map[string][]byte) map[string][]byte {
+	transient[protocolVersionKey] = []byte(testProtocolVersion)
+	return withHashAlgorithm(transient)
+}
+
+// newBatchFiles returns a batch file with two operations and an anchor file which references it
+func newBatchFiles() ([]byte, []byte) {
+	batch := []byte(`{"operations":["op1","op2"]}`)
+	anchor := []byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, encodedMultihash(batch)))
+
+	return batch, anchor
+}
+
+// writeFiles writes the given batch and anchor files to CAS and returns the anchor file address
+func writeFiles(t *testing.T, stub *mocks.MockStub, batch, anchor []byte) string {
+	_, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: batch}))
+	require.Nil(t, err)
+
+	address, err := invokeWithTransient(stub, [][]byte{[]byte(writeContent)}, withHashAlgorithm(map[string][]byte{contentKey: anchor}))
+	require.Nil(t, err)
+
+	return string(address)
+}
+
+func checkAnchorEvent(t *testing.T, stub *mocks.MockStub, anchorAddress string, operationCount int) {
+	event := getAnchorEvent(t, stub)
+	require.Equal(t, anchorAddress, event.AnchorAddress)
+	require.Equal(t, testProtocolVersion, event.ProtocolVersion)
+	require.Equal(t, operationCount, event.OperationCount)
+}
+
+func getAnchorEvent(t *testing.T, stub *mocks.MockStub) *anchorEvent {
+	require.NotNil(t, stub.Event)
+	require.Equal(t, anchorEventName, stub.Event.EventName)
+
+	event := &anchorEvent{}
+	require.Nil(t, json.Unmarshal(stub.Event.Payload, event))
+
+	return event
+}
+
+func batchFiles(batch, anchor []byte) map[string][]byte {
+	return withAnchorParams(map[string][]byte{batchFileKey: batch, anchorFileKey: anchor})
+}
+
+func invokeWithTransient(stub *mocks.MockStub, args [][]byte, transient map[string][]byte) ([]byte, error) {
+	stub.Transient = transient
+	defer func() { stub.Transient = make(map[string][]byte) }()
+
+	return invoke(stub, args)
+}
+
 func invoke(stub *mocks.MockStub, args [][]byte) ([]byte, error) {
 	txID := stub.GetTxID()
 	if txID == "" {
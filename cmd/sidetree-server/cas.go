@@ -0,0 +1,141 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+)
+
+const (
+	// casPath is the path prefix of the CAS endpoint (followed by the address of the content)
+	casPath = "/cas/"
+
+	// casCacheControl allows clients and proxies to cache content indefinitely since it is addressed by its hash
+	casCacheControl = "public, max-age=31536000, immutable"
+)
+
+// contentReader reads content from the content addressable storage of a namespace
+type contentReader interface {
+	Read(address string) ([]byte, error)
+}
+
+// serveCASContent returns the raw content at the address given in the path. The content is read from the
+// CAS of the namespace which prefixes the address (which is only required if the node serves more than one
+// namespace) or which is selected by the namespace header. Since the address is the hash of the content,
+// it is used as a strong ETag.
+func serveCASContent(w http.ResponseWriter, req *http.Request, router *namespaceRouter) {
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Message: http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+
+	h, address, err := router.forContent(req, strings.TrimPrefix(req.URL.Path, casPath))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorResponse{Message: err.Error()})
+		return
+	}
+
+	if address == "" || strings.Contains(address, "/") {
+		writeJSON(w, http.StatusBadRequest, &errorResponse{Message: "content address is required"})
+		return
+	}
+
+	if _, err = docutil.DecodeString(address); err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorResponse{Message: fmt.Sprintf("invalid content address [%s]", address)})
+		return
+	}
+
+	etag := strconv.Quote(address)
+
+	// content never changes so the client's copy is current if it has one
+	if etagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", casCacheControl)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	content, err := h.cas.Read(address)
+	if err != nil {
+		code := casErrorStatus(err)
+		if code == http.StatusInternalServerError {
+			logger.Errorf("Failed to read content at address [%s] of namespace %s: %s", address, h.namespace, err)
+		}
+
+		writeJSON(w, code, &errorResponse{Message: casErrorMessage(code, address)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", casCacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	if _, err = w.Write(content); err != nil {
+		logger.Warnf("Failed to write content at address [%s]: %s", address, err)
+	}
+}
+
+// casErrorStatus returns the status code of the given CAS read error
+func casErrorStatus(err error) int {
+
+	switch errors.Cause(err) {
+	case cas.ErrContentNotFound:
+		return http.StatusNotFound
+	case cas.ErrBadRequest:
+		return http.StatusBadRequest
+	case cas.ErrTransport:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// casErrorMessage returns the error message for the given status code. The error itself is not
+// returned since it contains the details of the peer responses.
+func casErrorMessage(code int, address string) string {
+
+	switch code {
+	case http.StatusNotFound:
+		return fmt.Sprintf("content at address [%s] not found", address)
+	case http.StatusBadRequest:
+		return fmt.Sprintf("invalid content address [%s]", address)
+	default:
+		return http.StatusText(code)
+	}
+}
+
+// etagMatches returns true if the given If-None-Match header matches the given entity tag (weak comparison)
+func etagMatches(header, etag string) bool {
+
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+)
+
+func TestServeCASContent(t *testing.T) {
+	content := []byte(`{"operations":[]}`)
+	address := docutil.EncodeToString([]byte("address"))
+
+	reader := &mockContentReader{content: map[string][]byte{address: content}}
+	router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", cas: reader}}}
+
+	rec := getCASContent(router, http.MethodGet, casPath+address, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, content, rec.Body.Bytes())
+	require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+	require.Equal(t, "17", rec.Header().Get("Content-Length"))
+	require.Equal(t, `"`+address+`"`, rec.Header().Get("ETag"))
+	require.Equal(t, casCacheControl, rec.Header().Get("Cache-Control"))
+	require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	t.Run("head", func(t *testing.T) {
+		rec := getCASContent(router, http.MethodHead, casPath+address, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Body.Bytes())
+		require.Equal(t, "17", rec.Header().Get("Content-Length"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		reads := reader.reads
+
+		for _, etag := range []string{`"` + address + `"`, `"other", W/"` + address + `"`} {
+			rec := getCASContent(router, http.MethodGet, casPath+address, http.Header{"If-None-Match": []string{etag}})
+			require.Equal(t, http.StatusNotModified, rec.Code, etag)
+			require.Empty(t, rec.Body.Bytes())
+			require.Equal(t, `"`+address+`"`, rec.Header().Get("ETag"))
+		}
+
+		// the content is not read
+		require.Equal(t, reads, reader.reads)
+
+		rec := getCASContent(router, http.MethodGet, casPath+address, http.Header{"If-None-Match": []string{`"other"`}})
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		other := docutil.EncodeToString([]byte("other"))
+
+		rec := getCASContent(router, http.MethodGet, casPath+other, nil)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), "content at address ["+other+"] not found")
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		for _, path := range []string{casPath, casPath + address + "/other"} {
+			rec := getCASContent(router, http.MethodGet, path, nil)
+			require.Equal(t, http.StatusBadRequest, rec.Code, path)
+			require.Contains(t, rec.Body.String(), "content address is required")
+		}
+
+		rec := getCASContent(router, http.MethodGet, casPath+"abc", nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "invalid content address [abc]")
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := getCASContent(router, http.MethodPost, casPath+address, nil)
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+
+	t.Run("namespace prefix", func(t *testing.T) {
+		test := &mockContentReader{content: map[string][]byte{address: []byte("test")}}
+		namespaces := &namespaceRouter{handlers: []*namespaceHandlers{
+			{namespace: "did:sidetree:", cas: reader},
+			{namespace: "did:sidetree:test:", cas: test},
+		}}
+
+		rec := getCASContent(namespaces, http.MethodGet, casPath+"did:sidetree:test:"+address, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "test", rec.Body.String())
+		require.Equal(t, `"`+address+`"`, rec.Header().Get("ETag"))
+
+		rec = getCASContent(namespaces, http.MethodGet, casPath+"did:sidetree:"+address, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, content, rec.Body.Bytes())
+
+		// the header overrides the prefix
+		rec = getCASContent(namespaces, http.MethodGet, casPath+address, http.Header{namespaceHeader: []string{"did:sidetree:test:"}})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "test", rec.Body.String())
+
+		rec = getCASContent(namespaces, http.MethodGet, casPath+address, nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "must be prefixed with its namespace")
+
+		rec = getCASContent(namespaces, http.MethodGet, casPath+"did:sidetree:test:", nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "content address is required")
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		rec := getCASContent(router, http.MethodGet, casPath+address, http.Header{namespaceHeader: []string{"did:other:"}})
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "namespace [did:other:] is not supported")
+	})
+}
+
+func TestServeCASContent_Error(t *testing.T) {
+	address := docutil.EncodeToString([]byte("address"))
+
+	for _, tc := range []struct {
+		err     error
+		code    int
+		message string
+	}{
+		{err: errors.Wrap(cas.ErrBadRequest, "peer error"), code: http.StatusBadRequest, message: "invalid content address"},
+		{err: errors.Wrap(cas.ErrTransport, "peer error"), code: http.StatusServiceUnavailable, message: http.StatusText(http.StatusServiceUnavailable)},
+		{err: &cas.IntegrityError{Address: address, ComputedAddress: "other"}, code: http.StatusInternalServerError, message: http.StatusText(http.StatusInternalServerError)},
+	} {
+		router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", cas: &mockContentReader{err: tc.err}}}}
+
+		rec := getCASContent(router, http.MethodGet, casPath+address, nil)
+		require.Equal(t, tc.code, rec.Code, tc.err.Error())
+		require.Contains(t, rec.Body.String(), tc.message)
+
+		// the details of the peer responses are not returned
+		require.NotContains(t, rec.Body.String(), "peer error")
+	}
+}
+
+func getCASContent(router *namespaceRouter, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	serveCASContent(rec, req, router)
+
+	return rec
+}
+
+type mockContentReader struct {
+	content map[string][]byte
+	err     error
+	reads   int
+}
+
+func (m *mockContentReader) Read(address string) ([]byte, error) {
+	m.reads++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	content, ok := m.content[address]
+	if !ok {
+		return nil, errors.Wrap(cas.ErrContentNotFound, "failed to read content at requested address")
+	}
+
+	return content, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-fabric/pkg/context"
+)
+
+const (
+	// healthPath is the path of the liveness endpoint
+	healthPath = "/healthz"
+	// readinessPath is the path of the readiness endpoint
+	readinessPath = "/readyz"
+
+	// readinessTimeout is the time to wait for a readiness check before it is reported as failed
+	readinessTimeout = 10 * time.Second
+
+	statusOK       = "ok"
+	statusFailed   = "failed"
+	statusReady    = "ready"
+	statusNotReady = "not ready"
+)
+
+// readinessCheck checks a dependency of a DID namespace. Nil is returned if the dependency is available.
+type readinessCheck struct {
+	namespace string
+	name      string
+	check     func() error
+}
+
+type checkResult struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks,omitempty"`
+}
+
+// healthHandler serves the liveness and readiness endpoints. The node is live as long as it serves
+// requests and ready if all readiness checks succeed.
+type healthHandler struct {
+	checks  []readinessCheck
+	timeout time.Duration
+}
+
+func newHealthHandler() *healthHandler {
+	return &healthHandler{timeout: readinessTimeout}
+}
+
+// addNamespace adds the readiness checks of the given namespace: the channel and chaincode are
+// reachable, the observer keeps up with the channel and the batch writer is running
+func (h *healthHandler) addNamespace(ctx *context.SidetreeContext, handlers *namespaceHandlers) {
+	namespace := ctx.Namespace()
+
+	h.checks = append(h.checks,
+		readinessCheck{namespace: namespace, name: "channel", check: ctx.CheckChannel},
+		readinessCheck{namespace: namespace, name: "chaincode", check: ctx.CheckChaincode},
+		readinessCheck{namespace: namespace, name: "observer", check: ctx.CheckObserver},
+		readinessCheck{namespace: namespace, name: "batchwriter", check: handlers.writer.check},
+	)
+}
+
+func (h *healthHandler) serveHealth(w http.ResponseWriter, req *http.Request) {
+	writeHealthResponse(w, http.StatusOK, &healthResponse{Status: statusOK})
+}
+
+func (h *healthHandler) serveReadiness(w http.ResponseWriter, req *http.Request) {
+
+	results := h.runChecks()
+
+	code := http.StatusOK
+	status := statusReady
+	for _, result := range results {
+		if result.Status != statusOK {
+			code = http.StatusServiceUnavailable
+			status = statusNotReady
+			break
+		}
+	}
+
+	writeHealthResponse(w, code, &healthResponse{Status: status, Checks: results})
+}
+
+// runChecks runs the readiness checks concurrently
+func (h *healthHandler) runChecks() []checkResult {
+
+	results := make([]checkResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, c readinessCheck) {
+			defer wg.Done()
+			results[i] = h.runCheck(c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (h *healthHandler) runCheck(c readinessCheck) checkResult {
+
+	result := checkResult{Namespace: c.namespace, Name: c.name, Status: statusOK}
+
+	// a check which does not complete in time (e.g. since a peer does not respond) keeps running in the background
+	errch := make(chan error, 1)
+	go func() { errch <- c.check() }()
+
+	var err error
+	select {
+	case err = <-errch:
+	case <-time.After(h.timeout):
+		err = errors.Errorf("timed out after %s", h.timeout)
+	}
+
+	if err != nil {
+		logger.Warnf("Readiness check [%s] of namespace %s failed: %s", c.name, c.namespace, err)
+
+		result.Status = statusFailed
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func writeHealthResponse(w http.ResponseWriter, code int, resp *healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Warnf("Failed to write health response: %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	h := &healthHandler{timeout: time.Second}
+
+	rec := httptest.NewRecorder()
+	h.serveHealth(rec, httptest.NewRequest(http.MethodGet, healthPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	resp := unmarshalHealthResponse(t, rec)
+	require.Equal(t, statusOK, resp.Status)
+	require.Empty(t, resp.Checks)
+}
+
+func TestReadiness(t *testing.T) {
+	writer := newOperationWriter(&mockBatchWriter{}, &mockQueue{}, &mockTracker{})
+
+	h := &healthHandler{
+		timeout: time.Second,
+		checks: []readinessCheck{
+			{namespace: "did:sidetree:", name: "channel", check: func() error { return nil }},
+			{namespace: "did:sidetree:", name: "batchwriter", check: writer.check},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	h.serveReadiness(rec, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	resp := unmarshalHealthResponse(t, rec)
+	require.Equal(t, statusReady, resp.Status)
+	require.Equal(t, []checkResult{
+		{Namespace: "did:sidetree:", Name: "channel", Status: statusOK},
+		{Namespace: "did:sidetree:", Name: "batchwriter", Status: statusOK},
+	}, resp.Checks)
+
+	writer.close()
+
+	rec = httptest.NewRecorder()
+	h.serveReadiness(rec, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	resp = unmarshalHealthResponse(t, rec)
+	require.Equal(t, statusNotReady, resp.Status)
+	require.Equal(t, checkResult{Namespace: "did:sidetree:", Name: "batchwriter", Status: statusFailed, Error: "batch writer is not running"}, resp.Checks[1])
+}
+
+func TestReadiness_CheckErrors(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	h := &healthHandler{
+		timeout: 50 * time.Millisecond,
+		checks: []readinessCheck{
+			{namespace: "did:sidetree:", name: "channel", check: func() error { return errors.New("connection failed") }},
+			{namespace: "did:sidetree:", name: "chaincode", check: func() error { <-block; return nil }},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	h.serveReadiness(rec, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	resp := unmarshalHealthResponse(t, rec)
+	require.Equal(t, statusNotReady, resp.Status)
+	require.Len(t, resp.Checks, 2)
+	require.Equal(t, "connection failed", resp.Checks[0].Error)
+	require.Equal(t, statusFailed, resp.Checks[1].Status)
+	require.Contains(t, resp.Checks[1].Error, "timed out")
+}
+
+func TestSetupGlobalMiddleware(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler := setupGlobalMiddleware(api, &healthHandler{timeout: time.Second}, &namespaceRouter{})
+
+	for path, code := range map[string]int{
+		healthPath:                http.StatusOK,
+		readinessPath:             http.StatusOK,
+		metricsPath:               http.StatusOK,
+		operationsPath + "hash":   http.StatusNotFound,
+		casPath + "address":       http.StatusBadRequest,
+		identifiersPath + testDID: http.StatusBadRequest,
+		"/document":               http.StatusTeapot,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, code, rec.Code, path)
+	}
+}
+
+func unmarshalHealthResponse(t *testing.T, rec *httptest.ResponseRecorder) *healthResponse {
+	resp := &healthResponse{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), resp))
+
+	return resp
+}
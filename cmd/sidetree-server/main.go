@@ -25,12 +25,13 @@ import (
 	"github.com/trustbloc/sidetree-core-go/pkg/dochandler/didvalidator"
 	"github.com/trustbloc/sidetree-core-go/pkg/processor"
 	"github.com/trustbloc/sidetree-fabric/pkg/context"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
 	"github.com/trustbloc/sidetree-node/pkg/requesthandler"
 	"github.com/trustbloc/sidetree-node/restapi"
 	"github.com/trustbloc/sidetree-node/restapi/operations"
 )
 
-const didDocNamespace = "did:sidetree:"
+var logger = logrus.New()
 
 func main() {
 
@@ -134,7 +135,6 @@ func configureAPI(api *operations.SidetreeAPI) (http.Handler, error) {
 	api.ApplicationJoseProducer = runtime.JSONProducer()
 	api.JSONProducer = runtime.JSONProducer()
 
-	var logger = logrus.New()
 	var config = viper.New()
 
 	config.SetEnvPrefix("SIDETREE_NODE")
@@ -143,61 +143,175 @@ func configureAPI(api *operations.SidetreeAPI) (http.Handler, error) {
 
 	logger.Info("starting sidetree node...")
 
-	ctx, err := context.New(config)
+	ctxs, err := context.New(config)
 	if err != nil {
 		logger.Errorf("Failed to create new context: %s", err.Error())
 		return nil, err
 	}
 
+	router := &namespaceRouter{}
+	health := newHealthHandler()
+	for _, ctx := range ctxs {
+		var handlers *namespaceHandlers
+		handlers, err = newNamespaceHandlers(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		router.handlers = append(router.handlers, handlers)
+		health.addNamespace(ctx, handlers)
+
+		logger.Infof("Serving DID namespace %s", ctx.Namespace())
+	}
+
+	api.PostDocumentHandler = operations.PostDocumentHandlerFunc(
+		func(params operations.PostDocumentParams) middleware.Responder {
+			handlers, e := router.forOperation(params.HTTPRequest, params.Request)
+			if e != nil {
+				return middleware.Error(http.StatusBadRequest, e.Error())
+			}
+
+			setRoutedNamespace(params.HTTPRequest, handlers.namespace)
+
+			return trackRejection(handlers.operation.HandleOperationRequest(params.Request), handlers.status, params.Request)
+		},
+	)
+	api.GetDocumentDidOrDidDocumentHandler = operations.GetDocumentDidOrDidDocumentHandlerFunc(
+		func(params operations.GetDocumentDidOrDidDocumentParams) middleware.Responder {
+			handlers, e := router.forDID(params.DidOrDidDocument)
+			if e != nil {
+				return middleware.Error(http.StatusBadRequest, e.Error())
+			}
+
+			return handlers.resolution.HandleResolveRequest(params.DidOrDidDocument)
+		},
+	)
+
+	shutdownTimeout := defaultShutdownTimeout
+	if config.IsSet(keyShutdownTimeout) {
+		shutdownTimeout = config.GetDuration(keyShutdownTimeout)
+	}
+
+	api.ServerShutdown = func() {
+		shutdown(router.handlers, shutdownTimeout)
+	}
+
+	return setupAndServe(api, health, router), nil
+}
+
+// newNamespaceHandlers starts the batch writer and observer of the given namespace context
+// and returns the request handlers of the namespace
+func newNamespaceHandlers(ctx *context.SidetreeContext) (*namespaceHandlers, error) {
+
+	namespace := ctx.Namespace()
+
 	// create new batch writer
 	batchWriter, err := batch.New(ctx)
 	if err != nil {
-		logger.Errorf("Failed to create batch writer: %s", err.Error())
+		logger.Errorf("Failed to create batch writer of namespace %s: %s", namespace, err.Error())
 		return nil, err
 	}
 
 	// start routine for creating batches
 	batchWriter.Start()
 
-	// did document handler with did document validator for didDocNamespace
+	writer := newOperationWriter(batchWriter, ctx.OperationQueue(), ctx.OperationStatus())
+
+	// operations which were not anchored before the node stopped are anchored now
+	replayed, err := writer.replay()
+	if err != nil {
+		logger.Errorf("Failed to replay the operation queue of namespace %s: %s", namespace, err.Error())
+		return nil, err
+	}
+
+	if replayed > 0 {
+		logger.Infof("Replayed %d queued operation(s) of namespace %s", replayed, namespace)
+	}
+
+	// start feeding anchored operations into the operation store
+	err = ctx.Observer().Start()
+	if err != nil {
+		logger.Errorf("Failed to start observer of namespace %s: %s", namespace, err.Error())
+		return nil, err
+	}
+
+	err = metrics.RegisterObserverHeight(namespace, ctx.Observer().BlockHeight)
+	if err != nil {
+		logger.Errorf("Failed to register observer metrics of namespace %s: %s", namespace, err.Error())
+		return nil, err
+	}
+
+	// did document handler with did document validator for the namespace
 	didDocHandler := dochandler.New(
-		didDocNamespace,
+		namespace,
 		ctx.Protocol(),
 		didvalidator.New(ctx.OperationStore()),
-		batchWriter,
+		writer,
 		processor.New(ctx.OperationStore()),
 	)
 
-	didResolutionHandler := requesthandler.NewResolutionHandler(didDocNamespace, ctx.Protocol(), didDocHandler)
-	didOperationHandler := requesthandler.NewOperationHandler(didDocNamespace, ctx.Protocol(), didDocHandler)
-
-	api.PostDocumentHandler = operations.PostDocumentHandlerFunc(
-		func(params operations.PostDocumentParams) middleware.Responder {
-			return didOperationHandler.HandleOperationRequest(params.Request)
-		},
-	)
-	api.GetDocumentDidOrDidDocumentHandler = operations.GetDocumentDidOrDidDocumentHandlerFunc(
-		func(params operations.GetDocumentDidOrDidDocumentParams) middleware.Responder {
-			return didResolutionHandler.HandleResolveRequest(params.DidOrDidDocument)
-		},
-	)
-	api.ServerShutdown = func() {}
-
-	return setupAndServe(api), nil
+	return &namespaceHandlers{
+		namespace:  namespace,
+		resolution: requesthandler.NewResolutionHandler(namespace, ctx.Protocol(), didDocHandler),
+		operations: ctx.OperationStore(),
+		operation:  requesthandler.NewOperationHandler(namespace, ctx.Protocol(), didDocHandler),
+		writer:     writer,
+		status:     ctx.OperationStatus(),
+		cas:        ctx.CASReader(),
+		ctx:        ctx,
+	}, nil
 }
 
-func setupAndServe(api *operations.SidetreeAPI) http.Handler {
-	return setupGlobalMiddleware(api.Serve(setupMiddlewares))
+func setupAndServe(api *operations.SidetreeAPI, health *healthHandler, router *namespaceRouter) http.Handler {
+	return setupGlobalMiddleware(api.Serve(setupMiddlewares), health, router)
 }
 
 // The middleware configuration is for the handler executors. These do not apply to the swagger.json document.
 // The middleware executes after routing but before authentication, binding and validation
 func setupMiddlewares(handler http.Handler) http.Handler {
-	return handler
+	// panics are recovered within the instrumentation so that they are counted as 500 responses
+	return instrumentRoutes(recoverPanics(handler))
 }
 
 // The middleware configuration happens before anything, this middleware also applies to serving the swagger.json document.
 // So this is a good place to plug in a panic handling middleware, logging and metrics
-func setupGlobalMiddleware(handler http.Handler) http.Handler {
-	return handler
+func setupGlobalMiddleware(handler http.Handler, health *healthHandler, router *namespaceRouter) http.Handler {
+	return withRequestID(logAccess(recoverPanics(routeEndpoints(handler, health, router)), router))
+}
+
+// routeEndpoints routes the requests of the health, metrics, operation status, CAS and Universal Resolver
+// driver endpoints. All other requests are served by the given handler. The operation status, CAS and
+// Universal Resolver driver endpoints are instrumented like the routes of the Sidetree API.
+func routeEndpoints(handler http.Handler, health *healthHandler, router *namespaceRouter) http.Handler {
+	operationStatus := instrumentRoute(operationsPath+"{hash}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serveOperationStatus(w, req, router)
+	}))
+
+	casContent := instrumentRoute(casPath+"{address}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serveCASContent(w, req, router)
+	}))
+
+	identifier := instrumentRoute(identifiersPath+"{identifier}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		serveIdentifier(w, req, router)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// these endpoints are not part of the Sidetree API
+		switch path := req.URL.Path; {
+		case path == healthPath:
+			health.serveHealth(w, req)
+		case path == readinessPath:
+			health.serveReadiness(w, req)
+		case path == metricsPath:
+			metrics.Handler().ServeHTTP(w, req)
+		case strings.HasPrefix(path, operationsPath):
+			operationStatus.ServeHTTP(w, req)
+		case strings.HasPrefix(path, casPath):
+			casContent.ServeHTTP(w, req)
+		case strings.HasPrefix(path, identifiersPath):
+			identifier.ServeHTTP(w, req)
+		default:
+			handler.ServeHTTP(w, req)
+		}
+	})
 }
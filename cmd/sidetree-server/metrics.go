@@ -0,0 +1,58 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+const (
+	// metricsPath is the path of the Prometheus metrics endpoint
+	metricsPath = "/metrics"
+
+	// unknownRoute labels the metrics of requests which were not routed to an operation
+	unknownRoute = "unknown"
+)
+
+// instrumentRoutes records the number and duration of API requests. Since it runs after routing, requests
+// are labeled with the path pattern of their route (e.g. /document/{didOrDidDocument}) rather than the path
+// of the request, which would create a time series per DID.
+func instrumentRoutes(handler http.Handler) http.Handler {
+	return instrument(handler, func(req *http.Request) string {
+		if matched := middleware.MatchedRouteFrom(req); matched != nil {
+			return matched.PathPattern
+		}
+		return unknownRoute
+	})
+}
+
+// instrumentRoute records the number and duration of the requests of an endpoint which is not part of the
+// Sidetree API (and therefore not routed by it). Requests are labeled with the given path pattern.
+func instrumentRoute(route string, handler http.Handler) http.Handler {
+	return instrument(handler, func(*http.Request) string {
+		return route
+	})
+}
+
+func instrument(handler http.Handler, routeFrom func(req *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		rec := newStatusRecorder(w)
+		handler.ServeHTTP(rec, req)
+
+		route := routeFrom(req)
+
+		metrics.RequestsTotal.WithLabelValues(route, req.Method, strconv.Itoa(rec.status)).Inc()
+		metrics.RequestDuration.WithLabelValues(route, req.Method).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+func TestInstrumentRoutes(t *testing.T) {
+	handler := instrumentRoutes(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	requests := metrics.RequestsTotal.WithLabelValues(unknownRoute, http.MethodGet, "418")
+	count := testutil.ToFloat64(requests)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/document/did:sidetree:123", nil))
+
+	require.Equal(t, http.StatusTeapot, rec.Code)
+	require.Equal(t, count+1, testutil.ToFloat64(requests))
+}
+
+func TestRouteEndpoints_Instrumented(t *testing.T) {
+	handler := routeEndpoints(http.NotFoundHandler(), &healthHandler{timeout: time.Second}, &namespaceRouter{})
+
+	// the endpoints which are not part of the Sidetree API are labeled with their path pattern
+	for _, test := range []struct {
+		path  string
+		route string
+		code  int
+	}{
+		{operationsPath + "hash", operationsPath + "{hash}", http.StatusNotFound},
+		{casPath + "address", casPath + "{address}", http.StatusBadRequest},
+		{identifiersPath + testDID, identifiersPath + "{identifier}", http.StatusBadRequest},
+	} {
+		requests := metrics.RequestsTotal.WithLabelValues(test.route, http.MethodGet, strconv.Itoa(test.code))
+		count := testutil.ToFloat64(requests)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		require.Equal(t, test.code, rec.Code, test.path)
+		require.Equal(t, count+1, testutil.ToFloat64(requests), test.path)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDHeader is the header which carries the ID of a request. The ID sent by the client (e.g. a proxy)
+// is used if it is valid, otherwise an ID is generated. The ID is returned in the response.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDRegex matches the request IDs which are accepted from clients. IDs with other characters
+// are replaced since they are written to the logs.
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// statusRecorder records the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+
+	return r.ResponseWriter.Write(b)
+}
+
+// withRequestID assigns an ID to each request
+func withRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+		if !requestIDRegex.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+
+		handler.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID assigned to the given request by withRequestID
+func requestID(req *http.Request) string {
+	id, _ := req.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logger.Warnf("Failed to generate request ID: %s", err)
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// logAccess logs each request once it was served. Requests of the health and metrics endpoints are
+// logged at debug level since they are polled. Request bodies are not logged.
+func logAccess(handler http.Handler, router *namespaceRouter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		req = withRoutedNamespace(req)
+
+		rec := newStatusRecorder(w)
+		handler.ServeHTTP(rec, req)
+
+		entry := logger.WithFields(logrus.Fields{
+			"requestID": requestID(req),
+			"method":    req.Method,
+			"path":      req.URL.Path,
+			"status":    rec.status,
+			"duration":  time.Since(start).String(),
+			"namespace": router.namespaceOf(req),
+		})
+
+		switch req.URL.Path {
+		case healthPath, readinessPath, metricsPath:
+			entry.Debug("Served request")
+		default:
+			entry.Info("Served request")
+		}
+	})
+}
+
+// recoverPanics turns a panic of the handler into a 500 response so that the client gets a response
+// and the panic is logged with the ID of the request
+func recoverPanics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := newStatusRecorder(w)
+
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			// the server aborts the response without logging
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			logger.WithField("requestID", requestID(req)).Errorf("Recovered from panic: %v\n%s", r, debug.Stack())
+
+			// the response cannot be replaced if the handler already started writing it
+			if rec.wroteHeader {
+				return
+			}
+
+			rec.Header().Set("Content-Type", "application/json")
+			rec.WriteHeader(http.StatusInternalServerError)
+
+			if err := json.NewEncoder(rec).Encode(&errorResponse{Message: http.StatusText(http.StatusInternalServerError)}); err != nil {
+				logger.Warnf("Failed to write error response: %s", err)
+			}
+		}()
+
+		handler.ServeHTTP(rec, req)
+	})
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
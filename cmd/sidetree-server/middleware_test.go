@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var id string
+	handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id = requestID(req)
+	}))
+
+	// an ID is generated
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/document", nil))
+	require.Len(t, id, 32)
+	require.Equal(t, id, rec.Header().Get(requestIDHeader))
+
+	// the ID of the client is used
+	req := httptest.NewRequest(http.MethodGet, "/document", nil)
+	req.Header.Set(requestIDHeader, "client-id.1")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, "client-id.1", id)
+	require.Equal(t, "client-id.1", rec.Header().Get(requestIDHeader))
+
+	// invalid IDs are replaced
+	req.Header.Set(requestIDHeader, "id\nforged log line")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Len(t, id, 32)
+
+	req.Header.Set(requestIDHeader, strings.Repeat("a", 65))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Len(t, id, 32)
+}
+
+func TestRecoverPanics(t *testing.T) {
+	handler := withRequestID(recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("handler failure")
+	})))
+
+	hook := test.NewLocal(logger)
+	defer hook.Reset()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/document", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"message":"Internal Server Error"}`, rec.Body.String())
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, logrus.ErrorLevel, entry.Level)
+	require.Contains(t, entry.Message, "Recovered from panic: handler failure")
+	require.Equal(t, rec.Header().Get(requestIDHeader), entry.Data["requestID"])
+
+	// the response is kept if the handler already wrote it
+	handler = recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("handler failure")
+	}))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/document", nil))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Empty(t, rec.Body.String())
+}
+
+func TestLogAccess(t *testing.T) {
+	router := &namespaceRouter{handlers: []*namespaceHandlers{
+		{namespace: "did:sidetree:"},
+		{namespace: "did:sidetree:test:"},
+	}}
+
+	handler := withRequestID(logAccess(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), router))
+
+	hook := test.NewLocal(logger)
+	defer hook.Reset()
+
+	level := logger.Level
+	logger.SetLevel(logrus.DebugLevel)
+	defer logger.SetLevel(level)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/document/did:sidetree:test:EiDOQXC2GnoVyHwIRbjhLx", nil))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, logrus.InfoLevel, entry.Level)
+	require.Equal(t, rec.Header().Get(requestIDHeader), entry.Data["requestID"])
+	require.Equal(t, http.MethodGet, entry.Data["method"])
+	require.Equal(t, "/document/did:sidetree:test:EiDOQXC2GnoVyHwIRbjhLx", entry.Data["path"])
+	require.Equal(t, http.StatusNotFound, entry.Data["status"])
+	require.Equal(t, "did:sidetree:test:", entry.Data["namespace"])
+	require.NotEmpty(t, entry.Data["duration"])
+
+	// the namespace of Universal Resolver requests is the namespace of the DID
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, identifiersPath+"did:sidetree:EiDOQXC2GnoVyHwIRbjhLx", nil))
+
+	entry = hook.LastEntry()
+	require.Equal(t, "did:sidetree:", entry.Data["namespace"])
+
+	// the namespace of operation requests is selected by the namespace header
+	req := httptest.NewRequest(http.MethodPost, "/document", strings.NewReader(`{"payload":"secret"}`))
+	req.Header.Set(namespaceHeader, "did:sidetree:")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry = hook.LastEntry()
+	require.Equal(t, "did:sidetree:", entry.Data["namespace"])
+	require.Equal(t, "/document", entry.Data["path"])
+
+	// the request body is not logged
+	line, err := entry.String()
+	require.Nil(t, err)
+	require.NotContains(t, line, "secret")
+
+	// polled endpoints are logged at debug level
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, readinessPath, nil))
+
+	entry = hook.LastEntry()
+	require.Equal(t, logrus.DebugLevel, entry.Level)
+	require.Equal(t, "", entry.Data["namespace"])
+
+	// operation requests without the header are logged with the namespace which the handler routed them to
+	handler = withRequestID(logAccess(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		setRoutedNamespace(req, "did:sidetree:test:")
+	}), router))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/document", nil))
+
+	entry = hook.LastEntry()
+	require.Equal(t, "did:sidetree:test:", entry.Data["namespace"])
+}
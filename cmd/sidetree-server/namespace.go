@@ -0,0 +1,211 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
+	"github.com/trustbloc/sidetree-node/models"
+	"github.com/trustbloc/sidetree-node/pkg/requesthandler"
+)
+
+// namespaceHeader is the request header which overrides the DID namespace of an operation or CAS request.
+// Requests are otherwise routed by the namespace prefix of the DID or content address which they address.
+const namespaceHeader = "X-DID-Namespace"
+
+// documentPath is the path prefix of resolution requests (followed by the DID or DID document)
+const documentPath = "/document/"
+
+type routedNamespaceKey struct{}
+
+// namespaceHandlers holds the request handlers of a DID namespace and the components which are shut down with them
+type namespaceHandlers struct {
+	namespace  string
+	resolution resolver
+	operations operationStore
+	operation  *requesthandler.OperationHandler
+	writer     *operationWriter
+	status     *opstatus.Tracker
+	cas        contentReader
+	ctx        namespaceContext
+}
+
+// namespaceRouter routes requests to the handlers of the DID namespace which they address
+type namespaceRouter struct {
+	handlers []*namespaceHandlers
+}
+
+// forPrefix returns the handlers of the namespace which is the longest prefix of the given value or nil
+func (r *namespaceRouter) forPrefix(value string) *namespaceHandlers {
+
+	var match *namespaceHandlers
+	for _, h := range r.handlers {
+		if strings.HasPrefix(value, h.namespace) && (match == nil || len(h.namespace) > len(match.namespace)) {
+			match = h
+		}
+	}
+
+	return match
+}
+
+// forHeader returns the handlers of the namespace selected by the namespace header of the given request.
+// False is returned if the request does not have the header.
+func (r *namespaceRouter) forHeader(req *http.Request) (*namespaceHandlers, bool, error) {
+
+	namespace := ""
+	if req != nil {
+		namespace = req.Header.Get(namespaceHeader)
+	}
+
+	if namespace == "" {
+		return nil, false, nil
+	}
+
+	for _, h := range r.handlers {
+		if h.namespace == namespace {
+			return h, true, nil
+		}
+	}
+
+	return nil, true, errors.Errorf("namespace [%s] is not supported", namespace)
+}
+
+// forDID returns the handlers of the namespace which is the longest prefix of the given DID
+func (r *namespaceRouter) forDID(did string) (*namespaceHandlers, error) {
+
+	if h := r.forPrefix(did); h != nil {
+		return h, nil
+	}
+
+	// the handlers of a single namespace reject DIDs of other namespaces themselves
+	if len(r.handlers) == 1 {
+		return r.handlers[0], nil
+	}
+
+	return nil, errors.Errorf("the namespace of DID [%s] is not supported", did)
+}
+
+// forOperation returns the handlers of the namespace which is the longest prefix of the DID in the payload
+// of the given operation request. The namespace header of the HTTP request overrides the payload.
+func (r *namespaceRouter) forOperation(req *http.Request, request *models.Request) (*namespaceHandlers, error) {
+
+	h, ok, err := r.forHeader(req)
+	if ok {
+		return h, err
+	}
+
+	if h = r.forPrefix(operationDID(request)); h != nil {
+		return h, nil
+	}
+
+	if len(r.handlers) == 1 {
+		return r.handlers[0], nil
+	}
+
+	return nil, errors.Errorf("the namespace of the operation cannot be determined from its payload, header [%s] is required since the node serves more than one namespace", namespaceHeader)
+}
+
+// forContent returns the handlers of the namespace which is the longest prefix of the given content address
+// together with the address without the prefix. The namespace header of the request overrides the prefix.
+func (r *namespaceRouter) forContent(req *http.Request, address string) (*namespaceHandlers, string, error) {
+
+	h, ok, err := r.forHeader(req)
+	if ok {
+		return h, address, err
+	}
+
+	if h = r.forPrefix(address); h != nil {
+		return h, strings.TrimPrefix(address, h.namespace), nil
+	}
+
+	if len(r.handlers) == 1 {
+		return r.handlers[0], address, nil
+	}
+
+	return nil, "", errors.Errorf("content address [%s] must be prefixed with its namespace since the node serves more than one namespace", address)
+}
+
+// namespaceOf returns the namespace addressed by the given request or an empty string
+// if the request does not address a namespace which is served by the node
+func (r *namespaceRouter) namespaceOf(req *http.Request) string {
+
+	// operation requests are routed by their payload which is only known to the handler
+	if namespace, ok := req.Context().Value(routedNamespaceKey{}).(*string); ok && *namespace != "" {
+		return *namespace
+	}
+
+	var h *namespaceHandlers
+	var err error
+	switch path := req.URL.Path; {
+	case strings.HasPrefix(path, documentPath):
+		h, err = r.forDID(strings.TrimPrefix(path, documentPath))
+	case strings.HasPrefix(path, identifiersPath):
+		h, err = r.forDID(strings.TrimPrefix(path, identifiersPath))
+	case strings.HasPrefix(path, casPath):
+		h, _, err = r.forContent(req, strings.TrimPrefix(path, casPath))
+	default:
+		h, err = r.forOperation(req, nil)
+	}
+
+	if err != nil {
+		return ""
+	}
+
+	return h.namespace
+}
+
+// withRoutedNamespace returns the given request with a context to which the handler of the request
+// records the namespace which the request was routed to
+func withRoutedNamespace(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), routedNamespaceKey{}, new(string)))
+}
+
+// setRoutedNamespace records the namespace which the given request was routed to
+func setRoutedNamespace(req *http.Request, namespace string) {
+	if req == nil {
+		return
+	}
+
+	if routed, ok := req.Context().Value(routedNamespaceKey{}).(*string); ok {
+		*routed = namespace
+	}
+}
+
+// operationDID returns the DID in the payload of the given operation request or an empty string
+// if the payload does not contain one
+func operationDID(request *models.Request) string {
+
+	if request == nil {
+		return ""
+	}
+
+	bytes, err := docutil.DecodeString(request.Payload)
+	if err != nil {
+		return ""
+	}
+
+	payload := &struct {
+		DID string `json:"did"`
+		ID  string `json:"id"`
+	}{}
+
+	if err := json.Unmarshal(bytes, payload); err != nil {
+		return ""
+	}
+
+	if payload.DID != "" {
+		return payload.DID
+	}
+
+	return payload.ID
+}
@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	"github.com/trustbloc/sidetree-node/models"
+)
+
+func TestNamespaceRouter_ForDID(t *testing.T) {
+	prod := &namespaceHandlers{namespace: "did:sidetree:"}
+	test := &namespaceHandlers{namespace: "did:sidetree:test:"}
+	other := &namespaceHandlers{namespace: "did:other:"}
+
+	router := &namespaceRouter{handlers: []*namespaceHandlers{prod, test, other}}
+
+	h, err := router.forDID("did:sidetree:EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == prod)
+
+	// the longest matching namespace is selected
+	h, err = router.forDID("did:sidetree:test:EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == test)
+
+	h, err = router.forDID("did:other:EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == other)
+
+	h, err = router.forDID("did:unknown:EiDOQXC2GnoVyHwIRbjhLx")
+	require.NotNil(t, err)
+	require.Nil(t, h)
+	require.Contains(t, err.Error(), "the namespace of DID [did:unknown:EiDOQXC2GnoVyHwIRbjhLx] is not supported")
+
+	// a single namespace handles all requests
+	router = &namespaceRouter{handlers: []*namespaceHandlers{prod}}
+
+	h, err = router.forDID("did:unknown:EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == prod)
+}
+
+func TestNamespaceRouter_ForOperation(t *testing.T) {
+	prod := &namespaceHandlers{namespace: "did:sidetree:"}
+	test := &namespaceHandlers{namespace: "did:sidetree:test:"}
+
+	router := &namespaceRouter{handlers: []*namespaceHandlers{prod, test}}
+
+	req, err := http.NewRequest(http.MethodPost, "/document", nil)
+	require.Nil(t, err)
+
+	// the operation is routed by the DID in its payload
+	h, err := router.forOperation(req, payloadRequest(`{"did":"did:sidetree:test:EiDOQXC2GnoVyHwIRbjhLx"}`))
+	require.Nil(t, err)
+	require.True(t, h == test)
+
+	h, err = router.forOperation(req, payloadRequest(`{"id":"did:sidetree:EiDOQXC2GnoVyHwIRbjhLx"}`))
+	require.Nil(t, err)
+	require.True(t, h == prod)
+
+	for _, request := range []*models.Request{
+		nil,
+		{Payload: "invalid"},
+		payloadRequest(`{"didUniqueSuffix":"EiDOQXC2GnoVyHwIRbjhLx"}`),
+		payloadRequest(`{"did":"did:other:EiDOQXC2GnoVyHwIRbjhLx"}`),
+	} {
+		h, err = router.forOperation(req, request)
+		require.NotNil(t, err)
+		require.Nil(t, h)
+		require.Contains(t, err.Error(), "header [X-DID-Namespace] is required")
+	}
+
+	// the header overrides the payload
+	req.Header.Set(namespaceHeader, "did:sidetree:test:")
+
+	h, err = router.forOperation(req, payloadRequest(`{"did":"did:sidetree:EiDOQXC2GnoVyHwIRbjhLx"}`))
+	require.Nil(t, err)
+	require.True(t, h == test)
+
+	req.Header.Set(namespaceHeader, "did:other:")
+
+	h, err = router.forOperation(req, nil)
+	require.NotNil(t, err)
+	require.Nil(t, h)
+	require.Contains(t, err.Error(), "namespace [did:other:] is not supported")
+
+	// a single namespace handles all operations
+	router = &namespaceRouter{handlers: []*namespaceHandlers{prod}}
+	req.Header.Del(namespaceHeader)
+
+	h, err = router.forOperation(req, payloadRequest(`{"didUniqueSuffix":"EiDOQXC2GnoVyHwIRbjhLx"}`))
+	require.Nil(t, err)
+	require.True(t, h == prod)
+}
+
+func TestNamespaceRouter_ForContent(t *testing.T) {
+	prod := &namespaceHandlers{namespace: "did:sidetree:"}
+	test := &namespaceHandlers{namespace: "did:sidetree:test:"}
+
+	router := &namespaceRouter{handlers: []*namespaceHandlers{prod, test}}
+
+	req, err := http.NewRequest(http.MethodGet, casPath, nil)
+	require.Nil(t, err)
+
+	h, address, err := router.forContent(req, "did:sidetree:test:EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == test)
+	require.Equal(t, "EiDOQXC2GnoVyHwIRbjhLx", address)
+
+	h, address, err = router.forContent(req, "EiDOQXC2GnoVyHwIRbjhLx")
+	require.NotNil(t, err)
+	require.Nil(t, h)
+	require.Empty(t, address)
+	require.Contains(t, err.Error(), "content address [EiDOQXC2GnoVyHwIRbjhLx] must be prefixed with its namespace")
+
+	req.Header.Set(namespaceHeader, "did:sidetree:")
+
+	h, address, err = router.forContent(req, "EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == prod)
+	require.Equal(t, "EiDOQXC2GnoVyHwIRbjhLx", address)
+
+	// the prefix is optional if a single namespace is served
+	router = &namespaceRouter{handlers: []*namespaceHandlers{prod}}
+	req.Header.Del(namespaceHeader)
+
+	h, address, err = router.forContent(req, "EiDOQXC2GnoVyHwIRbjhLx")
+	require.Nil(t, err)
+	require.True(t, h == prod)
+	require.Equal(t, "EiDOQXC2GnoVyHwIRbjhLx", address)
+}
+
+func payloadRequest(payload string) *models.Request {
+	return &models.Request{Payload: docutil.EncodeToString([]byte(payload))}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/trustbloc/sidetree-node/models"
+)
+
+// operationsPath is the path prefix of the operation status endpoint (followed by the operation hash)
+const operationsPath = "/operations/"
+
+// requestTracker records operation requests which were rejected
+type requestTracker interface {
+	RejectedRequest(encodedPayload string, reason string)
+}
+
+type operationStatusResponse struct {
+	Hash          string `json:"hash"`
+	Namespace     string `json:"namespace"`
+	Status        string `json:"status"`
+	AnchorAddress string `json:"anchorAddress,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	BlockNumber   uint64 `json:"blockNumber,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// serveOperationStatus returns the status of the operation with the hash given in the path. The hash is the
+// multihash of the encoded payload of the operation in base64url encoding (computed with the hash algorithm
+// of the protocol version in force when the operation was submitted). The namespaces are searched in turn
+// since the hash does not identify the namespace.
+func serveOperationStatus(w http.ResponseWriter, req *http.Request, router *namespaceRouter) {
+
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Message: http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+
+	hash := strings.TrimPrefix(req.URL.Path, operationsPath)
+	if hash == "" || strings.Contains(hash, "/") {
+		writeJSON(w, http.StatusBadRequest, &errorResponse{Message: "operation hash is required"})
+		return
+	}
+
+	for _, h := range router.handlers {
+		status, ok, err := h.status.Get(hash)
+		if err != nil {
+			logger.Errorf("Failed to get the status of operation [%s] of namespace %s: %s", hash, h.namespace, err)
+			writeJSON(w, http.StatusInternalServerError, &errorResponse{Message: http.StatusText(http.StatusInternalServerError)})
+			return
+		}
+
+		if !ok {
+			continue
+		}
+
+		resp := &operationStatusResponse{
+			Hash:      status.Hash,
+			Namespace: h.namespace,
+			Status:    string(status.Status),
+			Reason:    status.Reason,
+		}
+
+		if status.Anchor != nil {
+			resp.AnchorAddress = status.Anchor.Address
+			resp.TransactionID = status.Anchor.TxID
+			resp.BlockNumber = status.Anchor.BlockNumber
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, &errorResponse{Message: fmt.Sprintf("operation [%s] not found", hash)})
+}
+
+// trackRejection records the given operation request as rejected if the response of the given responder is an error
+func trackRejection(responder middleware.Responder, tracker requestTracker, request *models.Request) middleware.Responder {
+	return middleware.ResponderFunc(func(w http.ResponseWriter, producer runtime.Producer) {
+		rec := &bodyRecorder{statusRecorder: newStatusRecorder(w)}
+
+		responder.WriteResponse(rec, producer)
+
+		if rec.status >= http.StatusBadRequest && request != nil {
+			tracker.RejectedRequest(request.Payload, rejectionReason(rec.status, rec.body.Bytes()))
+		}
+	})
+}
+
+// bodyRecorder records the body of error responses
+type bodyRecorder struct {
+	*statusRecorder
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if r.status >= http.StatusBadRequest {
+		r.body.Write(b)
+	}
+
+	return r.statusRecorder.Write(b)
+}
+
+// rejectionReason returns the message of the given error response (or the status text if there is none)
+func rejectionReason(status int, body []byte) string {
+
+	var resp errorResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Message != "" {
+		return resp.Message
+	}
+
+	var message string
+	if err := json.Unmarshal(body, &message); err == nil && message != "" {
+		return message
+	}
+
+	return http.StatusText(status)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warnf("Failed to write response: %s", err)
+	}
+}
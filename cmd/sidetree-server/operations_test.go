@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	coreMocks "github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-node/models"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
+)
+
+func TestServeOperationStatus(t *testing.T) {
+	store := &mockOperationStore{}
+	tracker1 := opstatus.New(coreMocks.NewMockProtocolClient(), store, opstatus.Config{})
+	tracker2 := opstatus.New(coreMocks.NewMockProtocolClient(), &mockOperationStore{}, opstatus.Config{})
+
+	router := &namespaceRouter{handlers: []*namespaceHandlers{
+		{namespace: "did:sidetree:", status: tracker1},
+		{namespace: "did:sidetree:test:", status: tracker2},
+	}}
+
+	op := operationRequest("payload")
+	hash := payloadHash(t, "payload")
+
+	tracker2.Accepted(op)
+
+	rec := getOperationStatus(router, http.MethodGet, operationsPath+hash)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, &operationStatusResponse{Hash: hash, Namespace: "did:sidetree:test:", Status: "pending"}, unmarshalStatusResponse(t, rec))
+
+	tracker2.Anchored([][]byte{op}, opstatus.Anchor{Address: "address", TxID: "txID", BlockNumber: 5})
+
+	rec = getOperationStatus(router, http.MethodGet, operationsPath+hash)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, &operationStatusResponse{
+		Hash:          hash,
+		Namespace:     "did:sidetree:test:",
+		Status:        "anchored",
+		AnchorAddress: "address",
+		TransactionID: "txID",
+		BlockNumber:   5,
+	}, unmarshalStatusResponse(t, rec))
+
+	tracker2.Rejected([][]byte{op}, "failed to anchor batch")
+
+	rec = getOperationStatus(router, http.MethodGet, operationsPath+hash)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, &operationStatusResponse{Hash: hash, Namespace: "did:sidetree:test:", Status: "rejected", Reason: "failed to anchor batch"}, unmarshalStatusResponse(t, rec))
+
+	t.Run("operation store", func(t *testing.T) {
+		// operations which are not tracked are looked up in the operation store
+		store.ops = []batch.Operation{{
+			EncodedPayload:               "anchored",
+			HashAlgorithmInMultiHashCode: coreMocks.NewMockProtocolClient().Current().HashAlgorithmInMultiHashCode,
+			TransactionTime:              7,
+		}}
+		defer func() { store.ops = nil }()
+
+		anchoredHash := payloadHash(t, "anchored")
+
+		rec := getOperationStatus(router, http.MethodGet, operationsPath+anchoredHash)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, &operationStatusResponse{Hash: anchoredHash, Namespace: "did:sidetree:", Status: "anchored", BlockNumber: 7}, unmarshalStatusResponse(t, rec))
+	})
+
+	t.Run("operation store error", func(t *testing.T) {
+		store.err = errors.New("store error")
+		defer func() { store.err = nil }()
+
+		rec := getOperationStatus(router, http.MethodGet, operationsPath+"unknown")
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.NotContains(t, rec.Body.String(), "store error")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rec := getOperationStatus(router, http.MethodGet, operationsPath+"unknown")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), "operation [unknown] not found")
+	})
+
+	t.Run("missing hash", func(t *testing.T) {
+		for _, path := range []string{operationsPath, operationsPath + hash + "/other"} {
+			rec := getOperationStatus(router, http.MethodGet, path)
+			require.Equal(t, http.StatusBadRequest, rec.Code, path)
+			require.Contains(t, rec.Body.String(), "operation hash is required")
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := getOperationStatus(router, http.MethodPost, operationsPath+hash)
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Equal(t, http.MethodGet, rec.Header().Get("Allow"))
+	})
+}
+
+func TestTrackRejection(t *testing.T) {
+	tracker := opstatus.New(coreMocks.NewMockProtocolClient(), &mockOperationStore{}, opstatus.Config{})
+	hash := payloadHash(t, "payload")
+
+	// successful requests are tracked once the operation is accepted
+	rec := httptest.NewRecorder()
+	trackRejection(respond(http.StatusOK, `{}`), tracker, &models.Request{Payload: "payload"}).WriteResponse(rec, runtime.JSONProducer())
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	_, ok, err := tracker.Get(hash)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	rec = httptest.NewRecorder()
+	trackRejection(respond(http.StatusBadRequest, `{"message":"invalid operation"}`), tracker, &models.Request{Payload: "payload"}).WriteResponse(rec, runtime.JSONProducer())
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, `{"message":"invalid operation"}`, rec.Body.String())
+
+	status, ok, err := tracker.Get(hash)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, opstatus.StatusRejected, status.Status)
+	require.Equal(t, "invalid operation", status.Reason)
+
+	// requests without body are not tracked
+	rec = httptest.NewRecorder()
+	trackRejection(respond(http.StatusBadRequest, `{}`), tracker, nil).WriteResponse(rec, runtime.JSONProducer())
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRejectionReason(t *testing.T) {
+	require.Equal(t, "invalid operation", rejectionReason(http.StatusBadRequest, []byte(`{"message":"invalid operation"}`)))
+	require.Equal(t, "invalid operation", rejectionReason(http.StatusBadRequest, []byte(`"invalid operation"`)))
+	require.Equal(t, http.StatusText(http.StatusInternalServerError), rejectionReason(http.StatusInternalServerError, []byte("failure")))
+	require.Equal(t, http.StatusText(http.StatusBadRequest), rejectionReason(http.StatusBadRequest, nil))
+}
+
+func getOperationStatus(router *namespaceRouter, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	serveOperationStatus(rec, httptest.NewRequest(method, path, nil), router)
+
+	return rec
+}
+
+func unmarshalStatusResponse(t *testing.T, rec *httptest.ResponseRecorder) *operationStatusResponse {
+	resp := &operationStatusResponse{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), resp))
+
+	return resp
+}
+
+func respond(code int, body string) middleware.Responder {
+	return middleware.ResponderFunc(func(w http.ResponseWriter, producer runtime.Producer) {
+		w.WriteHeader(code)
+		_, err := w.Write([]byte(body))
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+func operationRequest(payload string) []byte {
+	return []byte(fmt.Sprintf(`{"header":{"operation":"create"},"payload":"%s","signature":"signature"}`, payload))
+}
+
+func payloadHash(t *testing.T, payload string) string {
+	hash, err := docutil.ComputeMultihash(coreMocks.NewMockProtocolClient().Current().HashAlgorithmInMultiHashCode, []byte(payload))
+	require.Nil(t, err)
+
+	return docutil.EncodeToString(hash)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+)
+
+const (
+	// identifiersPath is the path prefix of the Universal Resolver driver endpoint (followed by the DID)
+	identifiersPath = "/1.0/identifiers/"
+
+	// didResolutionContext is the JSON-LD context of DID resolution results
+	didResolutionContext = "https://w3id.org/did-resolution/v1"
+
+	// Media types of DID resolution results and DID documents
+	resolutionResultMediaType = `application/ld+json;profile="https://w3id.org/did-resolution"`
+	didLDJSONMediaType        = "application/did+ld+json"
+	didJSONMediaType          = "application/did+json"
+
+	// Errors of DID resolution results
+	errorInvalidDID         = "invalidDid"
+	errorNotFound           = "notFound"
+	errorMethodNotSupported = "methodNotSupported"
+	errorInternalError      = "internalError"
+)
+
+// resolutionResult is a DID resolution result
+type resolutionResult struct {
+	Context            string             `json:"@context"`
+	DIDDocument        json.RawMessage    `json:"didDocument"`
+	ResolutionMetadata resolutionMetadata `json:"didResolutionMetadata"`
+	DocumentMetadata   documentMetadata   `json:"didDocumentMetadata"`
+}
+
+type resolutionMetadata struct {
+	ContentType  string `json:"contentType,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// documentMetadata is the metadata of a resolved DID document (empty if the DID could not be resolved)
+type documentMetadata struct {
+	Method *methodMetadata `json:"method,omitempty"`
+}
+
+// methodMetadata is the Sidetree specific metadata of a resolved DID document
+type methodMetadata struct {
+	// Published is true if the operations of the document were anchored (false if the DID document
+	// was resolved from the document itself)
+	Published bool `json:"published"`
+	// Namespace is the DID namespace of the document
+	Namespace string `json:"namespace"`
+	// UniqueSuffix is the unique suffix of the DID
+	UniqueSuffix string `json:"uniqueSuffix,omitempty"`
+	// OperationCount is the number of anchored operations of the document
+	OperationCount int `json:"operationCount,omitempty"`
+	// Created is the anchor of the create operation of the document
+	Created *operationMetadata `json:"created,omitempty"`
+	// LastOperation is the last anchored operation of the document
+	LastOperation *operationMetadata `json:"lastOperation,omitempty"`
+}
+
+// operationMetadata identifies an anchored operation
+type operationMetadata struct {
+	Type              batch.OperationType `json:"type"`
+	TransactionTime   uint64              `json:"transactionTime"`
+	TransactionNumber uint64              `json:"transactionNumber"`
+}
+
+// resolver resolves DIDs (or DID documents) to DID documents
+type resolver interface {
+	HandleResolveRequest(id string) middleware.Responder
+}
+
+// operationStore returns the anchored operations of a DID document
+type operationStore interface {
+	Get(uniqueSuffix string) ([]batch.Operation, error)
+}
+
+// serveIdentifier resolves the DID given in the path with the resolution handler of its namespace. A DID
+// resolution result is returned unless the client only accepts a DID document (see resolutionMediaType).
+func serveIdentifier(w http.ResponseWriter, req *http.Request, router *namespaceRouter) {
+
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, &errorResponse{Message: http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+
+	mediaType := resolutionMediaType(req.Header.Get("Accept"))
+
+	did := strings.TrimPrefix(req.URL.Path, identifiersPath)
+	if did == "" {
+		writeResolutionError(w, mediaType, http.StatusBadRequest, errorInvalidDID, "DID is required")
+		return
+	}
+
+	h, err := router.forDID(did)
+	if err != nil {
+		writeResolutionError(w, mediaType, http.StatusBadRequest, errorMethodNotSupported, err.Error())
+		return
+	}
+
+	// the router falls back to the only namespace which is served, whether or not it matches the DID
+	if !strings.HasPrefix(did, h.namespace) {
+		writeResolutionError(w, mediaType, http.StatusBadRequest, errorMethodNotSupported, fmt.Sprintf("the namespace of DID [%s] is not supported", did))
+		return
+	}
+
+	code, body := captureResponse(h.resolution.HandleResolveRequest(did))
+	if code != http.StatusOK {
+		writeResolutionError(w, mediaType, code, resolutionError(code), rejectionReason(code, body))
+		return
+	}
+
+	if !json.Valid(body) {
+		logger.Errorf("Resolution of DID [%s] returned an invalid DID document", did)
+		writeResolutionError(w, mediaType, http.StatusInternalServerError, errorInternalError, "invalid DID document")
+		return
+	}
+
+	if mediaType != resolutionResultMediaType {
+		w.Header().Set("Content-Type", mediaType)
+		w.WriteHeader(http.StatusOK)
+
+		if _, err = w.Write(body); err != nil {
+			logger.Warnf("Failed to write DID document: %s", err)
+		}
+
+		return
+	}
+
+	writeResolutionResult(w, http.StatusOK, &resolutionResult{
+		Context:            didResolutionContext,
+		DIDDocument:        json.RawMessage(bytes.TrimSpace(body)),
+		ResolutionMetadata: resolutionMetadata{ContentType: didLDJSONMediaType},
+		DocumentMetadata:   documentMetadata{Method: resolveMethodMetadata(h, body)},
+	})
+}
+
+// resolveMethodMetadata returns the method metadata of the given resolved DID document. The unique suffix
+// is taken from the ID of the document and the operations of the document are read from the operation store
+// of its namespace. Failing to read the operations does not fail the resolution (the metadata is incomplete).
+func resolveMethodMetadata(h *namespaceHandlers, document []byte) *methodMetadata {
+
+	metadata := &methodMetadata{Namespace: h.namespace}
+
+	doc := &struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(document, doc); err != nil || !strings.HasPrefix(doc.ID, h.namespace) {
+		logger.Warnf("Unable to determine the unique suffix of the resolved DID document of namespace %s", h.namespace)
+		return metadata
+	}
+
+	metadata.UniqueSuffix = strings.TrimPrefix(doc.ID, h.namespace)
+
+	// a DID document which was resolved from the document itself has no operations
+	ops, err := h.operations.Get(metadata.UniqueSuffix)
+	if err != nil {
+		logger.Debugf("No anchored operations of DID [%s]: %s", doc.ID, err)
+		return metadata
+	}
+
+	if len(ops) == 0 {
+		return metadata
+	}
+
+	metadata.Published = true
+	metadata.OperationCount = len(ops)
+	metadata.LastOperation = newOperationMetadata(ops[len(ops)-1])
+
+	if ops[0].Type == batch.OperationTypeCreate {
+		metadata.Created = newOperationMetadata(ops[0])
+	}
+
+	return metadata
+}
+
+func newOperationMetadata(op batch.Operation) *operationMetadata {
+	return &operationMetadata{
+		Type:              op.Type,
+		TransactionTime:   op.TransactionTime,
+		TransactionNumber: op.TransactionNumber,
+	}
+}
+
+// resolutionMediaType returns the media type of the response to a resolution request with the given Accept
+// header. A DID document is returned if the client accepts a DID document media type but not a DID resolution
+// result. Otherwise a DID resolution result is returned.
+func resolutionMediaType(accept string) string {
+
+	var documentMediaType string
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case mediaType == "application/ld+json" && params["profile"] == "https://w3id.org/did-resolution":
+			return resolutionResultMediaType
+		case (mediaType == didLDJSONMediaType || mediaType == didJSONMediaType) && documentMediaType == "":
+			documentMediaType = mediaType
+		}
+	}
+
+	if documentMediaType != "" {
+		return documentMediaType
+	}
+
+	return resolutionResultMediaType
+}
+
+// resolutionError returns the DID resolution error for the given status code of the resolution handler
+func resolutionError(code int) string {
+
+	switch {
+	case code == http.StatusNotFound:
+		return errorNotFound
+	case code >= http.StatusBadRequest && code < http.StatusInternalServerError:
+		return errorInvalidDID
+	default:
+		return errorInternalError
+	}
+}
+
+// writeResolutionError writes a DID resolution result with the given error (or an error response
+// if the client requested a DID document)
+func writeResolutionError(w http.ResponseWriter, mediaType string, code int, resolutionErr, message string) {
+
+	if mediaType != resolutionResultMediaType {
+		writeJSON(w, code, &errorResponse{Message: message})
+		return
+	}
+
+	writeResolutionResult(w, code, &resolutionResult{
+		Context:            didResolutionContext,
+		DIDDocument:        json.RawMessage("null"),
+		ResolutionMetadata: resolutionMetadata{Error: resolutionErr, ErrorMessage: message},
+	})
+}
+
+func writeResolutionResult(w http.ResponseWriter, code int, result *resolutionResult) {
+	w.Header().Set("Content-Type", resolutionResultMediaType)
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Warnf("Failed to write DID resolution result: %s", err)
+	}
+}
+
+// captureResponse writes the response of the given responder (in JSON) to a buffer
+// and returns its status code and body
+func captureResponse(responder middleware.Responder) (int, []byte) {
+
+	buf := &responseBuffer{header: make(http.Header), status: http.StatusOK}
+	responder.WriteResponse(buf, runtime.JSONProducer())
+
+	return buf.status, buf.body.Bytes()
+}
+
+// responseBuffer is a response writer which buffers the response
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.status = code
+		b.wroteHeader = true
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+
+	return b.body.Write(p)
+}
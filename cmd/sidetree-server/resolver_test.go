@@ -0,0 +1,237 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const (
+	testUniqueSuffix = "EiDOQXC2GnoVyHwIRbjhLx"
+	testDID          = "did:sidetree:" + testUniqueSuffix
+	testDocument     = `{"@context":"https://w3id.org/did/v1","id":"did:sidetree:EiDOQXC2GnoVyHwIRbjhLx"}`
+)
+
+func TestServeIdentifier(t *testing.T) {
+	res := &mockResolver{code: http.StatusOK, body: testDocument + "\n"}
+	store := &mockOperationStore{ops: []batch.Operation{
+		{Type: batch.OperationTypeCreate, UniqueSuffix: testUniqueSuffix, TransactionTime: 5, TransactionNumber: 1},
+		{Type: batch.OperationTypeUpdate, UniqueSuffix: testUniqueSuffix, TransactionTime: 9, TransactionNumber: 4},
+	}}
+	router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", resolution: res, operations: store}}}
+
+	rec := getIdentifier(router, http.MethodGet, identifiersPath+testDID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, resolutionResultMediaType, rec.Header().Get("Content-Type"))
+	require.Equal(t, testDID, res.id)
+
+	result := unmarshalResolutionResult(t, rec)
+	require.Equal(t, didResolutionContext, result.Context)
+	require.JSONEq(t, testDocument, string(result.DIDDocument))
+	require.Equal(t, resolutionMetadata{ContentType: didLDJSONMediaType}, result.ResolutionMetadata)
+	require.Equal(t, testUniqueSuffix, store.uniqueSuffix)
+	require.Equal(t, documentMetadata{Method: &methodMetadata{
+		Published:      true,
+		Namespace:      "did:sidetree:",
+		UniqueSuffix:   testUniqueSuffix,
+		OperationCount: 2,
+		Created:        &operationMetadata{Type: batch.OperationTypeCreate, TransactionTime: 5, TransactionNumber: 1},
+		LastOperation:  &operationMetadata{Type: batch.OperationTypeUpdate, TransactionTime: 9, TransactionNumber: 4},
+	}}, result.DocumentMetadata)
+
+	t.Run("unpublished", func(t *testing.T) {
+		unpublished := &namespaceRouter{handlers: []*namespaceHandlers{{
+			namespace:  "did:sidetree:",
+			resolution: &mockResolver{code: http.StatusOK, body: testDocument},
+			operations: &mockOperationStore{err: errors.New("uniqueSuffix not found in the store")},
+		}}}
+
+		rec := getIdentifier(unpublished, http.MethodGet, identifiersPath+testDID, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, documentMetadata{Method: &methodMetadata{
+			Namespace:    "did:sidetree:",
+			UniqueSuffix: testUniqueSuffix,
+		}}, unmarshalResolutionResult(t, rec).DocumentMetadata)
+		require.Contains(t, rec.Body.String(), `"published":false`)
+	})
+
+	t.Run("DID document", func(t *testing.T) {
+		for _, mediaType := range []string{didLDJSONMediaType, didJSONMediaType} {
+			rec := getIdentifier(router, http.MethodGet, identifiersPath+testDID, mediaType)
+			require.Equal(t, http.StatusOK, rec.Code, mediaType)
+			require.Equal(t, mediaType, rec.Header().Get("Content-Type"))
+			require.JSONEq(t, testDocument, rec.Body.String())
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := getIdentifier(router, http.MethodPost, identifiersPath+testDID, "")
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Equal(t, http.MethodGet, rec.Header().Get("Allow"))
+	})
+}
+
+func TestServeIdentifier_Error(t *testing.T) {
+	for _, tc := range []struct {
+		code          int
+		body          string
+		resolutionErr string
+		message       string
+	}{
+		{code: http.StatusNotFound, body: `{"message":"document not found"}`, resolutionErr: errorNotFound, message: "document not found"},
+		{code: http.StatusBadRequest, body: `{"message":"invalid DID"}`, resolutionErr: errorInvalidDID, message: "invalid DID"},
+		{code: http.StatusInternalServerError, body: `"store error"`, resolutionErr: errorInternalError, message: "store error"},
+	} {
+		router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", resolution: &mockResolver{code: tc.code, body: tc.body}}}}
+
+		rec := getIdentifier(router, http.MethodGet, identifiersPath+testDID, "")
+		require.Equal(t, tc.code, rec.Code, tc.message)
+		require.Equal(t, resolutionResultMediaType, rec.Header().Get("Content-Type"))
+
+		result := unmarshalResolutionResult(t, rec)
+		require.Equal(t, "null", string(result.DIDDocument))
+		require.Equal(t, resolutionMetadata{Error: tc.resolutionErr, ErrorMessage: tc.message}, result.ResolutionMetadata)
+		require.Contains(t, rec.Body.String(), `"didDocumentMetadata":{}`)
+
+		// clients which only accept a DID document get an error response
+		rec = getIdentifier(router, http.MethodGet, identifiersPath+testDID, didLDJSONMediaType)
+		require.Equal(t, tc.code, rec.Code, tc.message)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), tc.message)
+	}
+
+	t.Run("invalid document", func(t *testing.T) {
+		router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", resolution: &mockResolver{code: http.StatusOK}}}}
+
+		rec := getIdentifier(router, http.MethodGet, identifiersPath+testDID, "")
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		require.Equal(t, resolutionMetadata{Error: errorInternalError, ErrorMessage: "invalid DID document"}, unmarshalResolutionResult(t, rec).ResolutionMetadata)
+	})
+
+	t.Run("missing DID", func(t *testing.T) {
+		router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", resolution: &mockResolver{}}}}
+
+		rec := getIdentifier(router, http.MethodGet, identifiersPath, "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, errorInvalidDID, unmarshalResolutionResult(t, rec).ResolutionMetadata.Error)
+	})
+
+	t.Run("unsupported namespace", func(t *testing.T) {
+		router := &namespaceRouter{handlers: []*namespaceHandlers{
+			{namespace: "did:sidetree:", resolution: &mockResolver{}},
+			{namespace: "did:sidetree:test:", resolution: &mockResolver{}},
+		}}
+
+		rec := getIdentifier(router, http.MethodGet, identifiersPath+"did:other:EiDOQXC2GnoVyHwIRbjhLx", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		metadata := unmarshalResolutionResult(t, rec).ResolutionMetadata
+		require.Equal(t, errorMethodNotSupported, metadata.Error)
+		require.Contains(t, metadata.ErrorMessage, "the namespace of DID [did:other:EiDOQXC2GnoVyHwIRbjhLx] is not supported")
+	})
+
+	t.Run("unsupported namespace with a single namespace", func(t *testing.T) {
+		res := &mockResolver{}
+		router := &namespaceRouter{handlers: []*namespaceHandlers{{namespace: "did:sidetree:", resolution: res}}}
+
+		rec := getIdentifier(router, http.MethodGet, identifiersPath+"did:other:EiDOQXC2GnoVyHwIRbjhLx", "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Empty(t, res.id)
+
+		metadata := unmarshalResolutionResult(t, rec).ResolutionMetadata
+		require.Equal(t, errorMethodNotSupported, metadata.Error)
+		require.Contains(t, metadata.ErrorMessage, "the namespace of DID [did:other:EiDOQXC2GnoVyHwIRbjhLx] is not supported")
+	})
+}
+
+func TestResolutionMediaType(t *testing.T) {
+	for accept, mediaType := range map[string]string{
+		"":                               resolutionResultMediaType,
+		"*/*":                            resolutionResultMediaType,
+		"application/json":               resolutionResultMediaType,
+		didLDJSONMediaType:               didLDJSONMediaType,
+		didJSONMediaType + ";q=0.9":      didJSONMediaType,
+		"text/html, " + didJSONMediaType: didJSONMediaType,
+		resolutionResultMediaType:        resolutionResultMediaType,
+		didLDJSONMediaType + ", " + resolutionResultMediaType: resolutionResultMediaType,
+	} {
+		require.Equal(t, mediaType, resolutionMediaType(accept), accept)
+	}
+}
+
+func getIdentifier(router *namespaceRouter, method, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	rec := httptest.NewRecorder()
+	serveIdentifier(rec, req, router)
+
+	return rec
+}
+
+func unmarshalResolutionResult(t *testing.T, rec *httptest.ResponseRecorder) *resolutionResult {
+	result := &resolutionResult{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), result))
+
+	return result
+}
+
+type mockResolver struct {
+	id   string
+	code int
+	body string
+}
+
+func (m *mockResolver) HandleResolveRequest(id string) middleware.Responder {
+	m.id = id
+
+	return middleware.ResponderFunc(func(w http.ResponseWriter, producer runtime.Producer) {
+		w.WriteHeader(m.code)
+		if _, err := w.Write([]byte(m.body)); err != nil {
+			panic(err)
+		}
+	})
+}
+
+type mockOperationStore struct {
+	uniqueSuffix string
+	ops          []batch.Operation
+	err          error
+}
+
+func (m *mockOperationStore) Get(uniqueSuffix string) ([]batch.Operation, error) {
+	m.uniqueSuffix = uniqueSuffix
+
+	return m.ops, m.err
+}
+
+func (m *mockOperationStore) GetByHash(hash string) (*batch.Operation, bool, error) {
+	if m.err != nil {
+		return nil, false, m.err
+	}
+
+	for i, op := range m.ops {
+		multihash, err := docutil.ComputeMultihash(op.HashAlgorithmInMultiHashCode, []byte(op.EncodedPayload))
+		if err == nil && docutil.EncodeToString(multihash) == hash {
+			return &m.ops[i], true, nil
+		}
+	}
+
+	return nil, false, nil
+}
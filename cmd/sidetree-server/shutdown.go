@@ -0,0 +1,87 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyShutdownTimeout is the time to wait for pending operations to be anchored on shutdown
+	// (environment variable SIDETREE_NODE_SHUTDOWN_TIMEOUT)
+	keyShutdownTimeout = "shutdown.timeout"
+
+	defaultShutdownTimeout = 30 * time.Second
+
+	// anchorPollInterval is the interval at which shutdown checks whether the pending operations were anchored
+	anchorPollInterval = 100 * time.Millisecond
+)
+
+// namespaceContext is the part of the Sidetree context of a namespace which is needed for shutdown
+type namespaceContext interface {
+	SubmittedOperations() uint64
+	Close()
+}
+
+// shutdown shuts down the given namespaces concurrently. The operations which were accepted are
+// anchored if possible before the timeout expires.
+func shutdown(handlers []*namespaceHandlers, timeout time.Duration) {
+
+	logger.Infof("Shutting down %d namespace(s) within %s", len(handlers), timeout)
+
+	deadline := time.Now().Add(timeout)
+
+	var wg sync.WaitGroup
+	for _, h := range handlers {
+		wg.Add(1)
+		go func(h *namespaceHandlers) {
+			defer wg.Done()
+			shutdownNamespace(h, deadline)
+		}(h)
+	}
+	wg.Wait()
+
+	logger.Info("Shutdown complete")
+}
+
+// shutdownNamespace stops accepting operations, cuts the pending batch, waits for the pending operations to be
+// anchored (until the deadline), stops the batch writer and closes the context which stops the observer and
+// closes the SDK
+func shutdownNamespace(h *namespaceHandlers, deadline time.Time) {
+
+	h.writer.close()
+	h.writer.cut()
+
+	if err := waitForAnchors(h.writer, h.ctx, deadline); err != nil {
+		logger.Warnf("Operations of namespace %s are lost: %s", h.namespace, err)
+	}
+
+	h.writer.Stop()
+	h.ctx.Close()
+
+	logger.Infof("Namespace %s was shut down", h.namespace)
+}
+
+// waitForAnchors waits until all operations which were accepted by the writer were submitted for anchoring
+func waitForAnchors(w *operationWriter, ctx namespaceContext, deadline time.Time) error {
+
+	for {
+		accepted, submitted := w.acceptedOperations(), ctx.SubmittedOperations()
+		if submitted >= accepted {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return errors.Errorf("%d operations were not anchored before the shutdown timeout", accepted-submitted)
+		}
+
+		time.Sleep(anchorPollInterval)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	bw1 := &mockBatchWriter{}
+	ctx1 := &mockNamespaceContext{}
+	h1 := &namespaceHandlers{namespace: "did:sidetree:", writer: newOperationWriter(bw1, &mockQueue{}, &mockTracker{}), ctx: ctx1}
+
+	bw2 := &mockBatchWriter{}
+	ctx2 := &mockNamespaceContext{}
+	h2 := &namespaceHandlers{namespace: "did:sidetree:test:", writer: newOperationWriter(bw2, &mockQueue{}, &mockTracker{}), ctx: ctx2}
+
+	require.Nil(t, h1.writer.Add([]byte("op1")))
+	require.Nil(t, h1.writer.Add([]byte("op2")))
+
+	// the pending batch is anchored while shutting down
+	go func() {
+		time.Sleep(2 * anchorPollInterval)
+		ctx1.submit(2)
+	}()
+
+	start := time.Now()
+	shutdown([]*namespaceHandlers{h1, h2}, time.Minute)
+	require.True(t, time.Since(start) < time.Minute)
+
+	for _, h := range []*namespaceHandlers{h1, h2} {
+		require.Equal(t, errWriterClosed, h.writer.Add([]byte("op")))
+	}
+
+	require.True(t, bw1.stopped)
+	require.True(t, bw2.stopped)
+	require.Equal(t, int32(1), ctx1.closed)
+	require.Equal(t, int32(1), ctx2.closed)
+}
+
+func TestShutdown_Cut(t *testing.T) {
+	ctx := &mockNamespaceContext{}
+	bw := &mockBatchCutter{}
+	bw.cut = func() { ctx.submit(uint64(bw.added)) }
+
+	h := &namespaceHandlers{namespace: "did:sidetree:", writer: newOperationWriter(bw, &mockQueue{}, &mockTracker{}), ctx: ctx}
+
+	require.Nil(t, h.writer.Add([]byte("op1")))
+	require.Nil(t, h.writer.Add([]byte("op2")))
+
+	// the pending batch is cut on shutdown rather than once the batch timeout expires
+	start := time.Now()
+	shutdown([]*namespaceHandlers{h}, time.Minute)
+	require.True(t, time.Since(start) < anchorPollInterval)
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&bw.cuts))
+	require.True(t, bw.stopped)
+	require.Equal(t, int32(1), ctx.closed)
+}
+
+func TestShutdown_Timeout(t *testing.T) {
+	bw := &mockBatchWriter{}
+	ctx := &mockNamespaceContext{}
+	h := &namespaceHandlers{namespace: "did:sidetree:", writer: newOperationWriter(bw, &mockQueue{}, &mockTracker{}), ctx: ctx}
+
+	require.Nil(t, h.writer.Add([]byte("op")))
+
+	err := waitForAnchors(h.writer, ctx, time.Now().Add(anchorPollInterval))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "1 operations were not anchored")
+
+	// the namespace is shut down even though the operation was not anchored
+	shutdown([]*namespaceHandlers{h}, anchorPollInterval)
+
+	require.True(t, bw.stopped)
+	require.Equal(t, int32(1), ctx.closed)
+}
+
+type mockNamespaceContext struct {
+	submitted uint64
+	closed    int32
+}
+
+func (m *mockNamespaceContext) submit(n uint64) {
+	atomic.AddUint64(&m.submitted, n)
+}
+
+func (m *mockNamespaceContext) SubmittedOperations() uint64 {
+	return atomic.LoadUint64(&m.submitted)
+}
+
+func (m *mockNamespaceContext) Close() {
+	atomic.AddInt32(&m.closed, 1)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+// errWriterClosed is returned for operations which are submitted while the node shuts down
+var errWriterClosed = errors.New("batch writer is not running")
+
+type batchWriter interface {
+	Add(operation []byte) error
+	Stop()
+}
+
+// batchCutter is implemented by batch writers which cut their pending batch on demand. Other batch
+// writers cut it once their batch timeout expires.
+type batchCutter interface {
+	Cut()
+}
+
+// operationQueue persists the operations which are not yet anchored
+type operationQueue interface {
+	Put(op []byte) (string, error)
+	Delete(key string) error
+	Operations() ([][]byte, error)
+}
+
+// operationTracker records the status of the operations which were passed to the batch writer
+type operationTracker interface {
+	Accepted(op []byte)
+	Rejected(ops [][]byte, reason string)
+}
+
+// operationWriter passes the operations of a namespace to its batch writer. Operations are persisted
+// in the operation queue before they are accepted (the anchor client removes them once their batch
+// is anchored) so that they are replayed if the node stops before. It counts the accepted operations
+// as pending (the anchor client counts them down once their batch was submitted) so that shutdown can
+// wait for them to be anchored. Operations are rejected once the writer is closed.
+type operationWriter struct {
+	batchWriter
+	queue    operationQueue
+	tracker  operationTracker
+	accepted uint64
+	lock     sync.RWMutex
+	closed   bool
+}
+
+func newOperationWriter(w batchWriter, queue operationQueue, tracker operationTracker) *operationWriter {
+	return &operationWriter{batchWriter: w, queue: queue, tracker: tracker}
+}
+
+func (w *operationWriter) Add(operation []byte) error {
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	key, err := w.queue.Put(operation)
+	if err != nil {
+		return err
+	}
+
+	if err = w.batchWriter.Add(operation); err != nil {
+		if e := w.queue.Delete(key); e != nil {
+			logger.Warnf("Failed to remove rejected operation from the operation queue: %s", e)
+		}
+
+		w.tracker.Rejected([][]byte{operation}, err.Error())
+
+		return err
+	}
+
+	w.accept(operation)
+
+	return nil
+}
+
+// replay passes the operations which were queued but not anchored before the node stopped to the batch
+// writer and returns their number. An operation whose batch was committed just before the node stopped
+// is anchored again (i.e. operations are anchored at least once).
+func (w *operationWriter) replay() (int, error) {
+
+	ops, err := w.queue.Operations()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, op := range ops {
+		if err = w.batchWriter.Add(op); err != nil {
+			return 0, errors.Wrap(err, "failed to replay queued operation")
+		}
+
+		w.accept(op)
+	}
+
+	return len(ops), nil
+}
+
+func (w *operationWriter) accept(operation []byte) {
+	atomic.AddUint64(&w.accepted, 1)
+	metrics.PendingOperations.Inc()
+
+	w.tracker.Accepted(operation)
+}
+
+// close rejects further operations. The operations which were accepted are still anchored
+// until the batch writer is stopped.
+func (w *operationWriter) close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.closed = true
+}
+
+// acceptedOperations returns the number of operations which were accepted by the batch writer
+func (w *operationWriter) acceptedOperations() uint64 {
+	return atomic.LoadUint64(&w.accepted)
+}
+
+// check is the readiness check of the writer
+func (w *operationWriter) check() error {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	return nil
+}
+
+// cut cuts the pending batch so that the accepted operations are anchored without waiting
+// for the batch timeout
+func (w *operationWriter) cut() {
+	cutter, ok := w.batchWriter.(batchCutter)
+	if !ok {
+		logger.Debugf("The batch writer cuts the pending batch once its batch timeout expires")
+		return
+	}
+
+	cutter.Cut()
+}
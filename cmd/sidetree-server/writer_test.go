@@ -0,0 +1,203 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+func TestOperationWriter(t *testing.T) {
+	bw := &mockBatchWriter{}
+	queue := &mockQueue{}
+	tracker := &mockTracker{}
+	w := newOperationWriter(bw, queue, tracker)
+	require.Nil(t, w.check())
+
+	pending := testutil.ToFloat64(metrics.PendingOperations)
+
+	require.Nil(t, w.Add([]byte("op")))
+	require.Equal(t, 1, bw.added)
+	require.Equal(t, uint64(1), w.acceptedOperations())
+	require.Equal(t, pending+1, testutil.ToFloat64(metrics.PendingOperations))
+
+	// accepted operations are queued and pending
+	require.Equal(t, [][]byte{[]byte("op")}, queue.queued())
+	require.Equal(t, [][]byte{[]byte("op")}, tracker.accepted)
+
+	// operations which are rejected by the batch writer are neither pending nor queued
+	bw.err = errors.New("batch writer error")
+
+	require.Equal(t, bw.err, w.Add([]byte("rejected")))
+	require.Equal(t, uint64(1), w.acceptedOperations())
+	require.Equal(t, pending+1, testutil.ToFloat64(metrics.PendingOperations))
+	require.Equal(t, [][]byte{[]byte("op")}, queue.queued())
+	require.Equal(t, [][]byte{[]byte("rejected")}, tracker.rejected)
+	require.Equal(t, "batch writer error", tracker.reason)
+
+	// operations which cannot be queued are not passed to the batch writer
+	bw.err = nil
+	queue.err = errors.New("queue error")
+
+	require.Equal(t, queue.err, w.Add([]byte("op")))
+	require.Equal(t, 1, bw.added)
+	require.Equal(t, uint64(1), w.acceptedOperations())
+	queue.err = nil
+
+	// operations are rejected once the writer is closed
+	bw.err = nil
+	w.close()
+
+	require.Equal(t, errWriterClosed, w.Add([]byte("op")))
+	require.Equal(t, 1, bw.added)
+	require.Equal(t, errWriterClosed, w.check())
+}
+
+func TestOperationWriter_Replay(t *testing.T) {
+	queue := &mockQueue{}
+	for _, op := range []string{"op1", "op2"} {
+		_, err := queue.Put([]byte(op))
+		require.Nil(t, err)
+	}
+
+	bw := &mockBatchWriter{}
+	tracker := &mockTracker{}
+	w := newOperationWriter(bw, queue, tracker)
+
+	pending := testutil.ToFloat64(metrics.PendingOperations)
+
+	n, err := w.replay()
+	require.Nil(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 2, bw.added)
+	require.Equal(t, uint64(2), w.acceptedOperations())
+	require.Equal(t, pending+2, testutil.ToFloat64(metrics.PendingOperations))
+
+	// replayed operations remain queued until they are anchored
+	require.Equal(t, [][]byte{[]byte("op1"), []byte("op2")}, queue.queued())
+	require.Equal(t, [][]byte{[]byte("op1"), []byte("op2")}, tracker.accepted)
+
+	t.Run("queue error", func(t *testing.T) {
+		w := newOperationWriter(&mockBatchWriter{}, &mockQueue{err: errors.New("queue error")}, &mockTracker{})
+
+		_, err := w.replay()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "queue error")
+	})
+
+	t.Run("batch writer error", func(t *testing.T) {
+		w := newOperationWriter(&mockBatchWriter{err: errors.New("batch writer error")}, queue, &mockTracker{})
+
+		_, err := w.replay()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "failed to replay queued operation: batch writer error")
+		require.Equal(t, uint64(0), w.acceptedOperations())
+	})
+}
+
+type mockBatchWriter struct {
+	added   int
+	stopped bool
+	err     error
+}
+
+// mockBatchCutter is a batch writer which cuts its pending batch on demand
+type mockBatchCutter struct {
+	mockBatchWriter
+	cut  func()
+	cuts int32
+}
+
+func (m *mockBatchCutter) Cut() {
+	atomic.AddInt32(&m.cuts, 1)
+
+	if m.cut != nil {
+		m.cut()
+	}
+}
+
+func (m *mockBatchWriter) Add(operation []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.added++
+
+	return nil
+}
+
+func (m *mockBatchWriter) Stop() {
+	m.stopped = true
+}
+
+type mockQueue struct {
+	keys []string
+	ops  map[string][]byte
+	err  error
+}
+
+func (m *mockQueue) Put(op []byte) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+
+	if m.ops == nil {
+		m.ops = make(map[string][]byte)
+	}
+
+	key := fmt.Sprintf("op%d", len(m.keys))
+	m.keys = append(m.keys, key)
+	m.ops[key] = op
+
+	return key, nil
+}
+
+func (m *mockQueue) Delete(key string) error {
+	delete(m.ops, key)
+	return nil
+}
+
+func (m *mockQueue) Operations() ([][]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.queued(), nil
+}
+
+func (m *mockQueue) queued() [][]byte {
+	var ops [][]byte
+	for _, key := range m.keys {
+		if op, ok := m.ops[key]; ok {
+			ops = append(ops, op)
+		}
+	}
+
+	return ops
+}
+
+type mockTracker struct {
+	accepted [][]byte
+	rejected [][]byte
+	reason   string
+}
+
+func (m *mockTracker) Accepted(op []byte) {
+	m.accepted = append(m.accepted, op)
+}
+
+func (m *mockTracker) Rejected(ops [][]byte, reason string) {
+	m.rejected = append(m.rejected, ops...)
+	m.reason = reason
+}
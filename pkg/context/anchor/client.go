@@ -0,0 +1,322 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchor
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/blockchain"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+const (
+	anchorBatchFcn = "anchorBatch"
+
+	// Transient map keys of the batch and anchor files, the multihash algorithm code used to calculate
+	// their addresses and the protocol version
+	batchFileKey       = "batchFile"
+	anchorFileKey      = "anchorFile"
+	hashAlgorithmKey   = "hashAlgorithm"
+	protocolVersionKey = "protocolVersion"
+)
+
+var logger = logrus.New()
+
+type txnClient interface {
+	Execute(request channel.Request) ([]byte, *txn.Receipt, error)
+}
+
+// protocolClient provides the hash algorithm and the name of the current protocol version
+type protocolClient interface {
+	Current() protocolApi.Protocol
+	CurrentVersion() string
+}
+
+// contentCache caches content which was written to CAS
+type contentCache interface {
+	Cache(address string, content []byte)
+}
+
+// operationQueue holds the operations which are not yet anchored
+type operationQueue interface {
+	Remove(ops [][]byte) error
+}
+
+// statusTracker tracks the status of the operations of anchored batches
+type statusTracker interface {
+	Pending(ops [][]byte)
+	Batched(ops [][]byte)
+	Anchored(ops [][]byte, anchor opstatus.Anchor)
+	Rejected(ops [][]byte, reason string)
+}
+
+// anchorFile defines the part of the anchor file schema needed to locate the batch file
+type anchorFile struct {
+	BatchFileHash string `json:"batchFileHash"`
+}
+
+// batchFile defines the part of the batch file schema which holds the (base64url encoded) operations of the batch
+type batchFile struct {
+	Operations []string `json:"operations"`
+}
+
+// pendingContent is content which was written by the batch writer but is not yet stored in CAS
+type pendingContent struct {
+	content       []byte
+	hashAlgorithm uint
+}
+
+// batchFiles holds the files of a batch which is to be anchored
+type batchFiles struct {
+	hashAlgorithm    uint
+	anchorFile       []byte
+	batchFileAddress string
+	batchFile        []byte
+}
+
+// Client implements both the CAS client and the blockchain client of the batch writer. Content which
+// is written is held until the anchor file which references it is written. The batch file, the anchor
+// file and the anchor are then stored in a single transaction (anchorBatch) so that content is never
+// stored without an anchor (or the reverse).
+type Client struct {
+	txnClient      txnClient
+	ccID           string
+	protocolClient protocolClient
+	cache          contentCache
+	queue          operationQueue
+	tracker        statusTracker
+	retryCfg       blockchain.RetryConfig
+
+	pendingLock sync.Mutex
+	pending     map[string]pendingContent
+
+	submitted uint64
+}
+
+// New returns a new anchor client which anchors batches with the given Sidetree transaction chaincode. Content
+// is added to the given cache once it was anchored. Batches which fail to be anchored with a retryable error
+// are retried according to the given retry configuration. The operations of anchored and permanently rejected
+// batches are removed from the given operation queue and their status is recorded with the given tracker.
+func New(tc txnClient, ccID string, pc protocolClient, cache contentCache, queue operationQueue, tracker statusTracker, retryCfg blockchain.RetryConfig) *Client {
+	return &Client{
+		txnClient:      tc,
+		ccID:           ccID,
+		protocolClient: pc,
+		cache:          cache,
+		queue:          queue,
+		tracker:        tracker,
+		retryCfg:       retryCfg,
+		pending:        make(map[string]pendingContent),
+	}
+}
+
+// Write holds the given content until the anchor file which references it is written
+// returns the multihash (computed with the hash algorithm of the current protocol version)
+// in base64url encoding which is the address of the content once it is anchored.
+func (c *Client) Write(content []byte) (string, error) {
+
+	hashAlgorithm := c.protocolClient.Current().HashAlgorithmInMultiHashCode
+
+	hash, err := docutil.ComputeMultihash(hashAlgorithm, content)
+	if err != nil {
+		return "", errors.Wrapf(err, "hash algorithm [%d] not supported", hashAlgorithm)
+	}
+
+	address := docutil.EncodeToString(hash)
+
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	c.pending[address] = pendingContent{content: content, hashAlgorithm: hashAlgorithm}
+
+	return address, nil
+}
+
+// WriteAnchor stores the anchor file at the given address and the batch file which it references
+// in CAS and records the anchor on the ledger in a single transaction. Both files must have been
+// written with this client.
+func (c *Client) WriteAnchor(anchorAddress string) error {
+
+	receipt, err := c.WriteAnchorWithReceipt(anchorAddress)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Anchor file [%s] was committed in transaction [%s] of block %d", anchorAddress, receipt.TxID, receipt.BlockNumber)
+
+	return nil
+}
+
+// WriteAnchorWithReceipt anchors the batch (see WriteAnchor) and waits for the transaction to be committed
+// returns the receipt of the transaction.
+func (c *Client) WriteAnchorWithReceipt(anchorAddress string) (*txn.Receipt, error) {
+
+	files, err := c.takePending(anchorAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	request := channel.Request{
+		ChaincodeID: c.ccID,
+		Fcn:         anchorBatchFcn,
+		TransientMap: map[string][]byte{
+			batchFileKey:       files.batchFile,
+			anchorFileKey:      files.anchorFile,
+			hashAlgorithmKey:   []byte(strconv.FormatUint(uint64(files.hashAlgorithm), 10)),
+			protocolVersionKey: []byte(c.protocolClient.CurrentVersion()),
+		},
+	}
+
+	ops, count, err := decodeBatch(files.batchFile)
+	if err != nil {
+		logger.Warnf("Failed to decode the batch of anchor file [%s]: %s", anchorAddress, err)
+	}
+
+	c.tracker.Batched(ops)
+
+	start := time.Now()
+
+	var receipt *txn.Receipt
+	err = blockchain.Retry(c.retryCfg, func() error {
+		var e error
+		_, receipt, e = c.txnClient.Execute(request)
+		return e
+	})
+
+	metrics.ObserveAnchor(start, err)
+	c.observeBatch(count)
+
+	if err != nil {
+		c.rejected(anchorAddress, ops, err)
+		return nil, errors.Wrap(err, "failed to anchor batch")
+	}
+
+	c.tracker.Anchored(ops, opstatus.Anchor{Address: anchorAddress, TxID: receipt.TxID, BlockNumber: receipt.BlockNumber})
+
+	c.cache.Cache(files.batchFileAddress, files.batchFile)
+	c.cache.Cache(anchorAddress, files.anchorFile)
+
+	// the operations are queued until the anchor is committed. They are replayed if the node
+	// stops before they are removed (i.e. they may be anchored twice).
+	if e := c.queue.Remove(ops); e != nil {
+		logger.Warnf("Failed to remove the operations of anchor file [%s] from the operation queue: %s", anchorAddress, e)
+	}
+
+	return receipt, nil
+}
+
+// rejected records that the operations of the batch with the given anchor file failed to be anchored. The
+// operations are reported as rejected and removed from the operation queue only if the batch was definitively
+// rejected (see blockchain.IsRejected). Otherwise they remain queued and pending since the error may have been
+// transient or the transaction may still be committed.
+func (c *Client) rejected(anchorAddress string, ops [][]byte, err error) {
+
+	if !blockchain.IsRejected(err) {
+		logger.Warnf("The operations of anchor file [%s] remain queued since the batch was not rejected: %s", anchorAddress, err)
+		c.tracker.Pending(ops)
+		return
+	}
+
+	c.tracker.Rejected(ops, errors.Wrap(err, "failed to anchor batch").Error())
+
+	if e := c.queue.Remove(ops); e != nil {
+		logger.Warnf("Failed to remove the operations of rejected anchor file [%s] from the operation queue: %s", anchorAddress, e)
+	}
+}
+
+// takePending removes the anchor file at the given address and the batch file which it references
+// from the pending content and returns them
+func (c *Client) takePending(anchorAddress string) (*batchFiles, error) {
+
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	anchor, ok := c.pending[anchorAddress]
+	if !ok {
+		return nil, errors.Errorf("anchor file [%s] was not written", anchorAddress)
+	}
+
+	delete(c.pending, anchorAddress)
+
+	var af anchorFile
+	err := json.Unmarshal(anchor.content, &af)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid anchor file [%s]", anchorAddress)
+	}
+
+	batch, ok := c.pending[af.BatchFileHash]
+	if !ok {
+		return nil, errors.Errorf("batch file [%s] was not written", af.BatchFileHash)
+	}
+
+	delete(c.pending, af.BatchFileHash)
+
+	if batch.hashAlgorithm != anchor.hashAlgorithm {
+		return nil, errors.Errorf("batch file [%s] and anchor file [%s] have different hash algorithms", af.BatchFileHash, anchorAddress)
+	}
+
+	return &batchFiles{
+		hashAlgorithm:    anchor.hashAlgorithm,
+		anchorFile:       anchor.content,
+		batchFileAddress: af.BatchFileHash,
+		batchFile:        batch.content,
+	}, nil
+}
+
+// SubmittedOperations returns the number of operations of the batches which were submitted
+// (whether or not they were anchored)
+func (c *Client) SubmittedOperations() uint64 {
+	return atomic.LoadUint64(&c.submitted)
+}
+
+// observeBatch records the size of a batch with the given number of operations. The operations of the
+// batch are no longer pending, whether or not the batch was anchored (or its operations could be decoded).
+func (c *Client) observeBatch(count int) {
+
+	atomic.AddUint64(&c.submitted, uint64(count))
+
+	metrics.BatchSize.Observe(float64(count))
+	metrics.PendingOperations.Sub(float64(count))
+}
+
+// decodeBatch returns the operations of the given batch file which could be decoded and the number of
+// operations in the batch file. The error of the first operation which could not be decoded is returned.
+func decodeBatch(content []byte) ([][]byte, int, error) {
+
+	var bf batchFile
+	if err := json.Unmarshal(content, &bf); err != nil {
+		return nil, 0, errors.Wrap(err, "invalid batch file")
+	}
+
+	var err error
+	ops := make([][]byte, 0, len(bf.Operations))
+	for i, encoded := range bf.Operations {
+		op, e := docutil.DecodeString(encoded)
+		if e != nil {
+			if err == nil {
+				err = errors.Wrapf(e, "invalid operation %d", i)
+			}
+			continue
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, len(bf.Operations), err
+}
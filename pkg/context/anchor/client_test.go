@@ -0,0 +1,390 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/blockchain"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
+
+const (
+	protocolVersion = "0.1"
+	sha256Code      = 18
+	ccID            = "sidetreetxn_cc"
+)
+
+func TestNew(t *testing.T) {
+	c := New(&mockTxnClient{}, ccID, newMockProtocolClient(), newMockCache(), &mockQueue{}, &mockTracker{}, blockchain.RetryConfig{})
+	require.NotNil(t, c)
+}
+
+func TestWriteAnchor(t *testing.T) {
+	cc := &mockTxnClient{}
+	cache := newMockCache()
+	queue := &mockQueue{}
+	tracker := &mockTracker{}
+
+	c := New(cc, ccID, newMockProtocolClient(), cache, queue, tracker, blockchain.RetryConfig{})
+
+	batch := []byte(fmt.Sprintf(`{"operations":["%s"]}`, docutil.EncodeToString([]byte("op1"))))
+	batchAddress, err := c.Write(batch)
+	require.Nil(t, err)
+	require.Equal(t, multihashAddress(t, batch), batchAddress)
+
+	anchor := []byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress))
+	anchorAddress, err := c.Write(anchor)
+	require.Nil(t, err)
+
+	// nothing is sent to the peers until the anchor is written
+	require.Equal(t, 0, cc.requests)
+
+	pending := testutil.ToFloat64(metrics.PendingOperations)
+
+	receipt, err := c.WriteAnchorWithReceipt(anchorAddress)
+	require.Nil(t, err)
+
+	// the operation of the batch is no longer pending
+	require.Equal(t, pending-1, testutil.ToFloat64(metrics.PendingOperations))
+	require.Equal(t, uint64(1), c.SubmittedOperations())
+
+	require.Equal(t, &txn.Receipt{TxID: "txID", BlockNumber: 5}, receipt)
+	require.Equal(t, 1, cc.requests)
+	require.Equal(t, ccID, cc.request.ChaincodeID)
+	require.Equal(t, anchorBatchFcn, cc.request.Fcn)
+	require.Empty(t, cc.request.Args)
+	require.Equal(t, batch, cc.request.TransientMap[batchFileKey])
+	require.Equal(t, anchor, cc.request.TransientMap[anchorFileKey])
+	require.Equal(t, []byte("18"), cc.request.TransientMap[hashAlgorithmKey])
+	require.Equal(t, []byte(protocolVersion), cc.request.TransientMap[protocolVersionKey])
+
+	// anchored content is cached
+	require.Equal(t, batch, cache.content[batchAddress])
+	require.Equal(t, anchor, cache.content[anchorAddress])
+
+	// the anchored operation is removed from the queue
+	require.Equal(t, [][]byte{[]byte("op1")}, queue.removed)
+
+	// and its status is tracked
+	require.Equal(t, [][]byte{[]byte("op1")}, tracker.batched)
+	require.Equal(t, [][]byte{[]byte("op1")}, tracker.anchored)
+	require.Equal(t, opstatus.Anchor{Address: anchorAddress, TxID: "txID", BlockNumber: 5}, tracker.anchor)
+
+	// the files are only anchored once
+	err = c.WriteAnchor(anchorAddress)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("anchor file [%s] was not written", anchorAddress))
+}
+
+func TestWriteAnchor_Error(t *testing.T) {
+	cc := &mockTxnClient{err: &txn.InvalidTxError{TxID: "txID1", Code: pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}}
+	cache := newMockCache()
+	queue := &mockQueue{}
+	tracker := &mockTracker{}
+
+	c := New(cc, ccID, newMockProtocolClient(), cache, queue, tracker, blockchain.RetryConfig{})
+
+	batchAddress, err := c.Write([]byte(fmt.Sprintf(`{"operations":["%s"]}`, docutil.EncodeToString([]byte("op1")))))
+	require.Nil(t, err)
+
+	anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+	require.Nil(t, err)
+
+	err = c.WriteAnchor(anchorAddress)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to anchor batch")
+	require.Empty(t, cache.content)
+
+	// the batch was definitively rejected, so its operations are removed from the queue
+	require.Equal(t, [][]byte{[]byte("op1")}, queue.removed)
+
+	// and are reported as rejected
+	require.Equal(t, [][]byte{[]byte("op1")}, tracker.rejected)
+	require.Contains(t, tracker.reason, "failed to anchor batch")
+	require.Nil(t, tracker.pending)
+	require.Nil(t, tracker.anchored)
+}
+
+func TestWriteAnchor_TransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"timeout", status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil)},
+		{"MVCC read conflict", &txn.InvalidTxError{TxID: "txID1", Code: pb.TxValidationCode_MVCC_READ_CONFLICT}},
+		{"unknown error", errors.New("channel error")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cc := &mockTxnClient{err: test.err}
+			queue := &mockQueue{}
+			tracker := &mockTracker{}
+
+			c := New(cc, ccID, newMockProtocolClient(), newMockCache(), queue, tracker, blockchain.RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+			batchAddress, err := c.Write([]byte(fmt.Sprintf(`{"operations":["%s"]}`, docutil.EncodeToString([]byte("op1")))))
+			require.Nil(t, err)
+
+			anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+			require.Nil(t, err)
+
+			err = c.WriteAnchor(anchorAddress)
+			require.NotNil(t, err)
+			require.Equal(t, test.err, errors.Cause(err))
+
+			// the batch may still be anchored (or anchored when the operations are replayed), so they remain queued
+			// and pending
+			require.Nil(t, queue.removed)
+			require.Equal(t, [][]byte{[]byte("op1")}, tracker.pending)
+			require.Nil(t, tracker.rejected)
+		})
+	}
+}
+
+func TestWriteAnchor_QueueError(t *testing.T) {
+	queue := &mockQueue{err: errors.New("queue error")}
+
+	c := New(&mockTxnClient{}, ccID, newMockProtocolClient(), newMockCache(), queue, &mockTracker{}, blockchain.RetryConfig{})
+
+	batchAddress, err := c.Write([]byte(fmt.Sprintf(`{"operations":["%s"]}`, docutil.EncodeToString([]byte("op1")))))
+	require.Nil(t, err)
+
+	anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+	require.Nil(t, err)
+
+	// the batch was anchored even though its operations could not be removed from the queue
+	err = c.WriteAnchor(anchorAddress)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), c.SubmittedOperations())
+}
+
+func TestWriteAnchor_InvalidOperation(t *testing.T) {
+	queue := &mockQueue{}
+	tracker := &mockTracker{}
+
+	c := New(&mockTxnClient{}, ccID, newMockProtocolClient(), newMockCache(), queue, tracker, blockchain.RetryConfig{})
+
+	batchAddress, err := c.Write([]byte(fmt.Sprintf(`{"operations":["op1","%s"]}`, docutil.EncodeToString([]byte("op2")))))
+	require.Nil(t, err)
+
+	anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+	require.Nil(t, err)
+
+	err = c.WriteAnchor(anchorAddress)
+	require.Nil(t, err)
+
+	// the operation which cannot be decoded is submitted as well
+	require.Equal(t, uint64(2), c.SubmittedOperations())
+	require.Equal(t, [][]byte{[]byte("op2")}, tracker.anchored)
+	require.Equal(t, [][]byte{[]byte("op2")}, queue.removed)
+}
+
+func TestDecodeBatch(t *testing.T) {
+	ops, count, err := decodeBatch([]byte(fmt.Sprintf(`{"operations":["%s","%s"]}`, docutil.EncodeToString([]byte("op1")), docutil.EncodeToString([]byte("op2")))))
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("op1"), []byte("op2")}, ops)
+	require.Equal(t, 2, count)
+
+	_, count, err = decodeBatch([]byte(`{"operations":`))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid batch file")
+	require.Equal(t, 0, count)
+
+	// operations which cannot be decoded are counted
+	ops, count, err = decodeBatch([]byte(fmt.Sprintf(`{"operations":["op1","%s"]}`, docutil.EncodeToString([]byte("op2")))))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid operation 0")
+	require.Equal(t, [][]byte{[]byte("op2")}, ops)
+	require.Equal(t, 2, count)
+}
+
+func TestWriteAnchor_Retry(t *testing.T) {
+	mvccErr := &txn.InvalidTxError{TxID: "txID1", Code: pb.TxValidationCode_MVCC_READ_CONFLICT}
+
+	cc := &mockTxnClient{errs: []error{mvccErr, mvccErr}}
+	cache := newMockCache()
+
+	c := New(cc, ccID, newMockProtocolClient(), cache, &mockQueue{}, &mockTracker{}, blockchain.RetryConfig{InitialBackoff: time.Millisecond})
+
+	batchAddress, err := c.Write([]byte(`{"operations":[]}`))
+	require.Nil(t, err)
+
+	anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+	require.Nil(t, err)
+
+	receipt, err := c.WriteAnchorWithReceipt(anchorAddress)
+	require.Nil(t, err)
+	require.Equal(t, "txID", receipt.TxID)
+	require.Equal(t, 3, cc.requests)
+	require.Len(t, cache.content, 2)
+}
+
+func TestWriteAnchor_MissingFiles(t *testing.T) {
+	c := New(&mockTxnClient{}, ccID, newMockProtocolClient(), newMockCache(), &mockQueue{}, &mockTracker{}, blockchain.RetryConfig{})
+
+	anchorAddress, err := c.Write([]byte(`{"batchFileHash":"address"}`))
+	require.Nil(t, err)
+
+	err = c.WriteAnchor(anchorAddress)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "batch file [address] was not written")
+
+	anchorAddress, err = c.Write([]byte("invalid"))
+	require.Nil(t, err)
+
+	err = c.WriteAnchor(anchorAddress)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "invalid anchor file")
+}
+
+func TestWriteAnchor_HashAlgorithmChanged(t *testing.T) {
+	pc := newMockProtocolClient()
+
+	c := New(&mockTxnClient{}, ccID, pc, newMockCache(), &mockQueue{}, &mockTracker{}, blockchain.RetryConfig{})
+
+	batchAddress, err := c.Write([]byte(`{"operations":[]}`))
+	require.Nil(t, err)
+
+	// the anchor file is written with a different hash algorithm
+	c.pending[batchAddress] = pendingContent{content: c.pending[batchAddress].content, hashAlgorithm: 17}
+
+	anchorAddress, err := c.Write([]byte(fmt.Sprintf(`{"batchFileHash":"%s"}`, batchAddress)))
+	require.Nil(t, err)
+
+	err = c.WriteAnchor(anchorAddress)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "have different hash algorithms")
+}
+
+func TestWrite_UnsupportedHashAlgorithm(t *testing.T) {
+	pc := newMockProtocolClient()
+	pc.protocol.HashAlgorithmInMultiHashCode = 55
+
+	c := New(&mockTxnClient{}, ccID, pc, newMockCache(), &mockQueue{}, &mockTracker{}, blockchain.RetryConfig{})
+
+	address, err := c.Write([]byte("content"))
+	require.NotNil(t, err)
+	require.Empty(t, address)
+	require.Contains(t, err.Error(), "hash algorithm [55] not supported")
+}
+
+func multihashAddress(t *testing.T, content []byte) string {
+	hash, err := docutil.ComputeMultihash(sha256Code, content)
+	require.Nil(t, err)
+
+	return docutil.EncodeToString(hash)
+}
+
+type mockTxnClient struct {
+	request  channel.Request
+	requests int
+	errs     []error
+	err      error
+}
+
+func (m *mockTxnClient) Execute(request channel.Request) ([]byte, *txn.Receipt, error) {
+	m.request = request
+	m.requests++
+
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, nil, err
+	}
+
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	return nil, &txn.Receipt{TxID: "txID", BlockNumber: 5}, nil
+}
+
+type mockProtocolClient struct {
+	protocol protocol.Protocol
+}
+
+func newMockProtocolClient() *mockProtocolClient {
+	return &mockProtocolClient{protocol: protocol.Protocol{HashAlgorithmInMultiHashCode: sha256Code}}
+}
+
+func (m *mockProtocolClient) Current() protocol.Protocol {
+	return m.protocol
+}
+
+func (m *mockProtocolClient) CurrentVersion() string {
+	return protocolVersion
+}
+
+type mockCache struct {
+	content map[string][]byte
+}
+
+func newMockCache() *mockCache {
+	return &mockCache{content: make(map[string][]byte)}
+}
+
+func (m *mockCache) Cache(address string, content []byte) {
+	m.content[address] = content
+}
+
+type mockQueue struct {
+	removed [][]byte
+	err     error
+}
+
+func (m *mockQueue) Remove(ops [][]byte) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.removed = append(m.removed, ops...)
+
+	return nil
+}
+
+type mockTracker struct {
+	pending  [][]byte
+	batched  [][]byte
+	anchored [][]byte
+	anchor   opstatus.Anchor
+	rejected [][]byte
+	reason   string
+}
+
+func (m *mockTracker) Pending(ops [][]byte) {
+	m.pending = append(m.pending, ops...)
+}
+
+func (m *mockTracker) Batched(ops [][]byte) {
+	m.batched = append(m.batched, ops...)
+}
+
+func (m *mockTracker) Anchored(ops [][]byte, anchor opstatus.Anchor) {
+	m.anchored = append(m.anchored, ops...)
+	m.anchor = anchor
+}
+
+func (m *mockTracker) Rejected(ops [][]byte, reason string) {
+	m.rejected = append(m.rejected, ops...)
+	m.reason = reason
+}
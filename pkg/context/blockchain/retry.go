@@ -0,0 +1,221 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blockchain
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+)
+
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	defaultBackoffFactor  = 2.0
+
+	// gRPC status codes which indicate that the peer or orderer is temporarily unavailable
+	grpcDeadlineExceeded = 4
+	grpcUnavailable      = 14
+)
+
+var logger = logrus.New()
+
+// RetryConfig holds the retry policy for writing anchors. Zero values are replaced by defaults.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts (including the first one)
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time to wait between attempts
+	MaxBackoff time.Duration
+	// BackoffFactor is the factor by which the backoff is increased after each retry
+	BackoffFactor float64
+}
+
+func (cfg RetryConfig) withDefaults() RetryConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.BackoffFactor < 1 {
+		cfg.BackoffFactor = defaultBackoffFactor
+	}
+	return cfg
+}
+
+// Retry invokes the given function until it succeeds, fails with an error which is not retryable
+// (see IsRetryable) or the maximum number of attempts is reached. The wait time between
+// attempts grows exponentially. The error of the last attempt is returned.
+func Retry(cfg RetryConfig, fn func() error) error {
+
+	cfg = cfg.withDefaults()
+
+	backoff := cfg.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		if !IsRetryable(err) {
+			return err
+		}
+
+		if attempt >= cfg.MaxAttempts {
+			return errors.Wrapf(err, "giving up after %d attempts", attempt)
+		}
+
+		logger.Warnf("Attempt %d of %d failed with retryable error - retrying in %s: %s", attempt, cfg.MaxAttempts, backoff, err)
+
+		time.Sleep(backoff)
+
+		backoff = time.Duration(float64(backoff) * cfg.BackoffFactor)
+		if backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
+
+// IsRetryable returns true if the given error is a transient Fabric error which guarantees that the
+// transaction was not committed: the transaction was invalidated by a concurrent transaction (MVCC or
+// phantom read conflict), the endorsements did not match or a peer or orderer was unavailable before
+// the transaction was delivered. Timeouts are not retryable since the transaction may already have been
+// submitted to the orderer and retrying it could record the anchor twice (see MayHaveBeenSubmitted).
+// All other errors (e.g. a chaincode error or an endorsement policy failure) are permanent.
+func IsRetryable(err error) bool {
+
+	if invalidTxErr, ok := errors.Cause(err).(*txn.InvalidTxError); ok {
+		return invalidTxErr.Code == pb.TxValidationCode_MVCC_READ_CONFLICT ||
+			invalidTxErr.Code == pb.TxValidationCode_PHANTOM_READ_CONFLICT
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch s.Group {
+	case status.GRPCTransportStatus:
+		return s.Code == grpcUnavailable
+	case status.EndorserClientStatus:
+		// the transaction is only submitted to the orderer once it was endorsed
+		return s.Code == status.ConnectionFailed.ToInt32() || s.Code == status.Timeout.ToInt32()
+	case status.OrdererClientStatus:
+		return s.Code == status.ConnectionFailed.ToInt32()
+	case status.ClientStatus:
+		return isRetryableClientStatus(s)
+	default:
+		return false
+	}
+}
+
+func isRetryableClientStatus(s *status.Status) bool {
+
+	switch s.Code {
+	case status.EndorsementMismatch.ToInt32(), status.NoPeersFound.ToInt32():
+		return true
+	case status.MultipleErrors.ToInt32():
+		// for example, one error per endorser - retry only if all of them are transient
+		return allDetails(s, IsRetryable)
+	default:
+		return false
+	}
+}
+
+// MayHaveBeenSubmitted returns true if the outcome of the transaction is unknown, i.e. it may have been
+// submitted to the orderer and may still be committed: the orderer or the commit of the transaction timed
+// out or the gRPC deadline was exceeded.
+func MayHaveBeenSubmitted(err error) bool {
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch s.Group {
+	case status.GRPCTransportStatus:
+		return s.Code == grpcDeadlineExceeded
+	case status.OrdererClientStatus:
+		return s.Code == status.Timeout.ToInt32()
+	case status.ClientStatus:
+		if s.Code == status.MultipleErrors.ToInt32() {
+			return anyDetail(s, MayHaveBeenSubmitted)
+		}
+		return s.Code == status.Timeout.ToInt32()
+	default:
+		return false
+	}
+}
+
+// IsRejected returns true if the given error proves that the transaction was rejected and will never be
+// committed: the committer invalidated it for a reason other than a read conflict (e.g. an endorsement
+// policy failure) or the endorsers rejected the proposal (e.g. a chaincode error). The outcome of
+// retryable errors, timeouts and unknown errors is not definitive.
+func IsRejected(err error) bool {
+
+	if invalidTxErr, ok := errors.Cause(err).(*txn.InvalidTxError); ok {
+		return invalidTxErr.Code != pb.TxValidationCode_MVCC_READ_CONFLICT &&
+			invalidTxErr.Code != pb.TxValidationCode_PHANTOM_READ_CONFLICT
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch s.Group {
+	case status.ChaincodeStatus, status.EndorserServerStatus:
+		return true
+	case status.ClientStatus:
+		return s.Code == status.MultipleErrors.ToInt32() && allDetails(s, IsRejected)
+	default:
+		return false
+	}
+}
+
+// allDetails returns true if the status has details and all of them are errors which satisfy the given predicate
+func allDetails(s *status.Status, pred func(error) bool) bool {
+
+	if len(s.Details) == 0 {
+		return false
+	}
+
+	for _, detail := range s.Details {
+		detailErr, ok := detail.(error)
+		if !ok || !pred(detailErr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// anyDetail returns true if any of the details of the status is an error which satisfies the given predicate
+func anyDetail(s *status.Status, pred func(error) bool) bool {
+
+	for _, detail := range s.Details {
+		if detailErr, ok := detail.(error); ok && pred(detailErr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,196 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+)
+
+func TestRetry(t *testing.T) {
+	cfg := RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	mvccErr := &txn.InvalidTxError{TxID: "txID", Code: pb.TxValidationCode_MVCC_READ_CONFLICT}
+
+	attempts := 0
+	err := Retry(cfg, func() error {
+		attempts++
+		if attempts < 3 {
+			return mvccErr
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 3, attempts)
+
+	// gives up after the maximum number of attempts
+	attempts = 0
+	err = Retry(cfg, func() error {
+		attempts++
+		return mvccErr
+	})
+	require.NotNil(t, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, mvccErr, errors.Cause(err))
+	require.Contains(t, err.Error(), "giving up after 3 attempts")
+
+	// permanent errors are returned immediately
+	testErr := errors.New("permanent error")
+
+	attempts = 0
+	err = Retry(cfg, func() error {
+		attempts++
+		return testErr
+	})
+	require.Equal(t, testErr, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	cfg := RetryConfig{MaxAttempts: 4, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, BackoffFactor: 3}
+	unavailableErr := status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil)
+
+	var times []time.Time
+	err := Retry(cfg, func() error {
+		times = append(times, time.Now())
+		return unavailableErr
+	})
+	require.NotNil(t, err)
+	require.Len(t, times, 4)
+
+	// 10ms, then 30ms capped at 20ms, then 20ms
+	require.True(t, times[1].Sub(times[0]) >= 10*time.Millisecond)
+	require.True(t, times[2].Sub(times[1]) >= 20*time.Millisecond)
+	require.True(t, times[3].Sub(times[2]) >= 20*time.Millisecond)
+}
+
+func TestRetryConfig_Defaults(t *testing.T) {
+	cfg := RetryConfig{}.withDefaults()
+	require.Equal(t, defaultMaxAttempts, cfg.MaxAttempts)
+	require.Equal(t, defaultInitialBackoff, cfg.InitialBackoff)
+	require.Equal(t, defaultMaxBackoff, cfg.MaxBackoff)
+	require.Equal(t, defaultBackoffFactor, cfg.BackoffFactor)
+
+	cfg = RetryConfig{MaxAttempts: 1, InitialBackoff: time.Second, MaxBackoff: time.Minute, BackoffFactor: 1.5}.withDefaults()
+	require.Equal(t, RetryConfig{MaxAttempts: 1, InitialBackoff: time.Second, MaxBackoff: time.Minute, BackoffFactor: 1.5}, cfg)
+}
+
+func TestIsRetryable(t *testing.T) {
+	connectionFailed := status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil)
+	chaincodeErr := status.New(status.ChaincodeStatus, 500, "chaincode error", nil)
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"MVCC read conflict", &txn.InvalidTxError{Code: pb.TxValidationCode_MVCC_READ_CONFLICT}, true},
+		{"phantom read conflict", &txn.InvalidTxError{Code: pb.TxValidationCode_PHANTOM_READ_CONFLICT}, true},
+		{"endorsement policy failure", &txn.InvalidTxError{Code: pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, false},
+		{"wrapped MVCC read conflict", errors.Wrap(&txn.InvalidTxError{Code: pb.TxValidationCode_MVCC_READ_CONFLICT}, "failed"), true},
+		{"gRPC unavailable", status.New(status.GRPCTransportStatus, grpcUnavailable, "unavailable", nil), true},
+		{"gRPC deadline exceeded", status.New(status.GRPCTransportStatus, grpcDeadlineExceeded, "deadline exceeded", nil), false},
+		{"gRPC invalid argument", status.New(status.GRPCTransportStatus, 3, "invalid argument", nil), false},
+		{"endorser connection failed", connectionFailed, true},
+		{"endorser timeout", status.New(status.EndorserClientStatus, status.Timeout.ToInt32(), "timeout", nil), true},
+		{"orderer connection failed", status.New(status.OrdererClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil), true},
+		{"orderer timeout", status.New(status.OrdererClientStatus, status.Timeout.ToInt32(), "timeout", nil), false},
+		{"client timeout", status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil), false},
+		{"endorsement mismatch", status.New(status.ClientStatus, status.EndorsementMismatch.ToInt32(), "mismatch", nil), true},
+		{"no peers found", status.New(status.ClientStatus, status.NoPeersFound.ToInt32(), "no peers", nil), true},
+		{"chaincode error", chaincodeErr, false},
+		{"endorser server error", status.New(status.EndorserServerStatus, 500, "error", nil), false},
+		{"all endorsers unavailable", multi.Errors{connectionFailed, connectionFailed}, true},
+		{"endorser returned chaincode error", multi.Errors{connectionFailed, chaincodeErr}, false},
+		{"other error", errors.New("error"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.retryable, IsRetryable(test.err))
+		})
+	}
+}
+
+func TestRetry_Timeout(t *testing.T) {
+	cfg := RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	timeoutErr := status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil)
+
+	// the transaction may have been submitted, so it must not be resubmitted
+	attempts := 0
+	err := Retry(cfg, func() error {
+		attempts++
+		return timeoutErr
+	})
+	require.Equal(t, timeoutErr, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestMayHaveBeenSubmitted(t *testing.T) {
+	clientTimeout := status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil)
+	connectionFailed := status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil)
+
+	tests := []struct {
+		name      string
+		err       error
+		submitted bool
+	}{
+		{"client timeout", clientTimeout, true},
+		{"orderer timeout", status.New(status.OrdererClientStatus, status.Timeout.ToInt32(), "timeout", nil), true},
+		{"gRPC deadline exceeded", status.New(status.GRPCTransportStatus, grpcDeadlineExceeded, "deadline exceeded", nil), true},
+		{"multiple errors with timeout", multi.Errors{connectionFailed, clientTimeout}, true},
+		{"gRPC unavailable", status.New(status.GRPCTransportStatus, grpcUnavailable, "unavailable", nil), false},
+		{"orderer connection failed", status.New(status.OrdererClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil), false},
+		{"endorser timeout", status.New(status.EndorserClientStatus, status.Timeout.ToInt32(), "timeout", nil), false},
+		{"MVCC read conflict", &txn.InvalidTxError{Code: pb.TxValidationCode_MVCC_READ_CONFLICT}, false},
+		{"chaincode error", status.New(status.ChaincodeStatus, 500, "chaincode error", nil), false},
+		{"other error", errors.New("error"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.submitted, MayHaveBeenSubmitted(test.err))
+		})
+	}
+}
+
+func TestIsRejected(t *testing.T) {
+	chaincodeErr := status.New(status.ChaincodeStatus, 500, "chaincode error", nil)
+	connectionFailed := status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil)
+
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{"endorsement policy failure", &txn.InvalidTxError{Code: pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, true},
+		{"wrapped endorsement policy failure", errors.Wrap(&txn.InvalidTxError{Code: pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, "failed"), true},
+		{"MVCC read conflict", &txn.InvalidTxError{Code: pb.TxValidationCode_MVCC_READ_CONFLICT}, false},
+		{"phantom read conflict", &txn.InvalidTxError{Code: pb.TxValidationCode_PHANTOM_READ_CONFLICT}, false},
+		{"chaincode error", chaincodeErr, true},
+		{"endorser server error", status.New(status.EndorserServerStatus, 500, "error", nil), true},
+		{"all endorsers returned chaincode errors", multi.Errors{chaincodeErr, chaincodeErr}, true},
+		{"endorser unavailable", multi.Errors{connectionFailed, chaincodeErr}, false},
+		{"client timeout", status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil), false},
+		{"orderer connection failed", status.New(status.OrdererClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil), false},
+		{"other error", errors.New("error"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.rejected, IsRejected(test.err))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cas
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	defaultCacheSize     = 1000
+	defaultCacheMaxBytes = 100 * 1024 * 1024
+)
+
+// CacheConfig holds the limits of the content cache
+type CacheConfig struct {
+	// Size is the maximum number of cached entries
+	Size int
+	// MaxBytes is the maximum total size of the cached content
+	MaxBytes int
+}
+
+// cache is a LRU cache of content by address which is bounded by the number of entries and the total size
+// of the content. Since content is addressed by its hash it is immutable and entries never become stale.
+type cache struct {
+	lock     sync.Mutex
+	maxSize  int
+	maxBytes int
+	bytes    int
+	entries  *list.List
+	elements map[string]*list.Element
+}
+
+type cacheEntry struct {
+	address string
+	content []byte
+}
+
+func newCache(cfg CacheConfig) *cache {
+
+	maxSize := cfg.Size
+	if maxSize <= 0 {
+		maxSize = defaultCacheSize
+	}
+
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultCacheMaxBytes
+	}
+
+	return &cache{
+		maxSize:  maxSize,
+		maxBytes: maxBytes,
+		entries:  list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached content of the given address
+func (c *cache) get(address string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.elements[address]
+	if !ok {
+		return nil, false
+	}
+
+	c.entries.MoveToFront(elem)
+
+	return elem.Value.(*cacheEntry).content, true
+}
+
+// put caches the content of the given address and evicts the least recently used entries
+// until the cache is within its limits. Content which exceeds the byte limit is not cached.
+func (c *cache) put(address string, content []byte) {
+	if len(content) > c.maxBytes {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.elements[address]; ok {
+		c.entries.MoveToFront(elem)
+		return
+	}
+
+	c.elements[address] = c.entries.PushFront(&cacheEntry{address: address, content: content})
+	c.bytes += len(content)
+
+	for c.entries.Len() > c.maxSize || c.bytes > c.maxBytes {
+		c.evict()
+	}
+}
+
+func (c *cache) evict() {
+	elem := c.entries.Back()
+	entry := elem.Value.(*cacheEntry)
+
+	c.entries.Remove(elem)
+	delete(c.elements, entry.address)
+	c.bytes -= len(entry.content)
+}
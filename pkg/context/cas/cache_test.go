@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Defaults(t *testing.T) {
+	c := newCache(CacheConfig{})
+	require.Equal(t, defaultCacheSize, c.maxSize)
+	require.Equal(t, defaultCacheMaxBytes, c.maxBytes)
+}
+
+func TestCache_EvictBySize(t *testing.T) {
+	c := newCache(CacheConfig{Size: 2, MaxBytes: 100})
+
+	c.put("a", []byte("1"))
+	c.put("b", []byte("2"))
+
+	// "a" becomes the most recently used entry
+	content, ok := c.get("a")
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), content)
+
+	c.put("c", []byte("3"))
+
+	_, ok = c.get("b")
+	require.False(t, ok)
+
+	_, ok = c.get("a")
+	require.True(t, ok)
+
+	_, ok = c.get("c")
+	require.True(t, ok)
+}
+
+func TestCache_EvictByBytes(t *testing.T) {
+	c := newCache(CacheConfig{Size: 10, MaxBytes: 10})
+
+	c.put("a", []byte("1234"))
+	c.put("b", []byte("5678"))
+	c.put("b", []byte("5678"))
+	require.Equal(t, 8, c.bytes)
+
+	c.put("c", []byte("90"))
+	require.Equal(t, 10, c.bytes)
+
+	c.put("d", []byte("1"))
+	require.Equal(t, 7, c.bytes)
+
+	_, ok := c.get("a")
+	require.False(t, ok)
+
+	// content which exceeds the byte limit is not cached
+	c.put("e", []byte("12345678901"))
+
+	_, ok = c.get("e")
+	require.False(t, ok)
+	require.Equal(t, 7, c.bytes)
+}
@@ -7,102 +7,217 @@ SPDX-License-Identifier: Apache-2.0
 package cas
 
 import (
-	"sync"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
 
 	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
-	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
 )
 
 const (
-	sidetreeTxnCC = "sidetreetxn_cc"
-	writeFcn      = "writeContent"
-	readFcn       = "readContent"
+	writeFcn = "writeContent"
+	readFcn  = "readContent"
+
+	// Transient map keys of the content and the multihash algorithm code used to calculate its address
+	contentKey       = "content"
+	hashAlgorithmKey = "hashAlgorithm"
 )
 
 // Client implements client for accessing the underlying content addressable storage
 type Client struct {
-	lock            sync.RWMutex
-	channelProvider context.ChannelProvider
-	channelClient   chClient
+	txnClient      txnClient
+	ccID           string
+	protocolClient protocolApi.Client
+	cache          *cache
 }
 
-type chClient interface {
-	Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error)
-	Execute(request channel.Request, options ...channel.RequestOption) (channel.Response, error)
+type txnClient interface {
+	Query(request channel.Request) ([]byte, error)
+	Execute(request channel.Request) ([]byte, *txn.Receipt, error)
 }
 
-// New returns a new CAS client
-func New(channelProvider context.ChannelProvider) *Client {
+// New returns a new CAS client which stores content with the given Sidetree transaction chaincode. The protocol
+// client provides the hash algorithm used for content addresses. Content which is read or written is cached
+// within the limits of the given cache configuration.
+func New(tc txnClient, ccID string, pc protocolApi.Client, cacheCfg CacheConfig) *Client {
 
-	return &Client{channelProvider: channelProvider}
+	return &Client{txnClient: tc, ccID: ccID, protocolClient: pc, cache: newCache(cacheCfg)}
 }
 
 // Write writes the given content to content addressable storage
-// returns the SHA256 hash in base64url encoding which represents the address of the content.
+// returns the multihash (computed with the hash algorithm of the current protocol version)
+// in base64url encoding which represents the address of the content.
 func (c *Client) Write(content []byte) (string, error) {
 
-	client, err := c.getClient()
-	if err != nil {
-		return "", errors.Wrap(err, "failed to get channel client")
-	}
+	address, _, err := c.WriteWithReceipt(content)
+
+	return address, err
+}
+
+// WriteWithReceipt writes the given content to content addressable storage and waits for the transaction
+// to be committed. The content is passed in the transient map so that it is not included in the ordered transaction.
+// returns the address of the content and the receipt of the transaction.
+func (c *Client) WriteWithReceipt(content []byte) (string, *txn.Receipt, error) {
 
-	response, err := client.Execute(channel.Request{
-		ChaincodeID: sidetreeTxnCC,
+	hashAlgorithm := c.protocolClient.Current().HashAlgorithmInMultiHashCode
+
+	start := time.Now()
+
+	payload, receipt, err := c.txnClient.Execute(channel.Request{
+		ChaincodeID: c.ccID,
 		Fcn:         writeFcn,
-		Args:        [][]byte{content},
+		TransientMap: map[string][]byte{
+			contentKey:       content,
+			hashAlgorithmKey: []byte(strconv.FormatUint(uint64(hashAlgorithm), 10)),
+		},
 	})
 
+	metrics.ObserveCAS(metrics.CASWrite, start, err)
+
 	if err != nil {
-		return "", errors.Wrap(err, "failed to store content")
+		return "", nil, errors.Wrap(mapError(err), "failed to store content")
+	}
+
+	address := string(payload)
+
+	// only cache content which is known to be stored at the returned address
+	if verifyAddress(address, content) == nil {
+		c.cache.put(address, content)
 	}
 
-	return string(response.Payload), nil
+	return address, receipt, nil
+}
+
+// Cache caches content which was stored at the given address by other means than this client
+func (c *Client) Cache(address string, content []byte) {
+	c.cache.put(address, content)
+}
+
+// IntegrityError is returned by Read if the content returned by the peer does not hash to the requested address
+type IntegrityError struct {
+	// Address is the requested address
+	Address string
+	// ComputedAddress is the address computed from the returned content
+	ComputedAddress string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("content integrity check failed: content at address [%s] hashes to [%s]", e.Address, e.ComputedAddress)
 }
 
 // Read reads the content at the given address from content addressable storage
-// returns the content of the given address.
+// returns the content of the given address. The address is recomputed from the content
+// (with the hash algorithm encoded in the address) and an IntegrityError is returned on mismatch.
+// The cause of the returned error is ErrContentNotFound if there is no content at the address.
 func (c *Client) Read(address string) ([]byte, error) {
 
-	client, err := c.getClient()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get channel client")
+	if content, ok := c.cache.get(address); ok {
+		return content, nil
 	}
 
-	response, err := client.Query(channel.Request{
-		ChaincodeID: sidetreeTxnCC,
+	start := time.Now()
+
+	payload, err := c.txnClient.Query(channel.Request{
+		ChaincodeID: c.ccID,
 		Fcn:         readFcn,
 		Args:        [][]byte{[]byte(address)},
 	})
+
+	metrics.ObserveCAS(metrics.CASRead, start, err)
+
+	if err != nil {
+		return nil, errors.Wrap(mapError(err), "failed to read content at requested address")
+	}
+
+	err = verifyAddress(address, payload)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to read content at requested address")
+		return nil, err
 	}
 
-	return response.Payload, nil
+	c.cache.put(address, payload)
+
+	return payload, nil
 }
 
-func (c *Client) getClient() (chClient, error) {
+// verifyAddress verifies that the given content hashes to the given address. Multihash addresses are
+// computed the same way as the chaincode computes them. Legacy addresses (written before multihash
+// addressing was introduced) are plain SHA256 hashes.
+func verifyAddress(address string, content []byte) error {
 
-	c.lock.RLock()
-	chc := c.channelClient
-	c.lock.RUnlock()
+	hash, err := docutil.DecodeString(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address [%s]", address)
+	}
+
+	computed, err := computeHash(hash, content)
+	if err != nil {
+		return errors.Wrapf(err, "failed to verify content at address [%s]", address)
+	}
 
-	if chc != nil {
-		return chc, nil
+	if !bytes.Equal(hash, computed) {
+		return &IntegrityError{Address: address, ComputedAddress: docutil.EncodeToString(computed)}
 	}
 
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	return nil
+}
+
+// computeHash computes the hash of the content in the format of the given hash. Since a legacy
+// SHA256 hash may also parse as a multihash, the legacy hash is used if it matches.
+func computeHash(hash, content []byte) ([]byte, error) {
+
+	var legacyHash []byte
+	if len(hash) == sha256.Size {
+		h := sha256.Sum256(content)
+		legacyHash = h[:]
+
+		if bytes.Equal(hash, legacyHash) {
+			return legacyHash, nil
+		}
+	}
 
-	if c.channelClient == nil {
-		channelClient, err := channel.New(c.channelProvider)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create channel client")
+	hashAlgorithm, ok := multihashCode(hash)
+	if !ok {
+		if legacyHash != nil {
+			return legacyHash, nil
 		}
 
-		c.channelClient = channelClient
+		return nil, errors.New("address is neither a multihash nor a SHA256 hash")
+	}
+
+	computed, err := docutil.ComputeMultihash(hashAlgorithm, content)
+	if err != nil {
+		if legacyHash != nil {
+			return legacyHash, nil
+		}
+
+		return nil, errors.Wrapf(err, "hash algorithm [%d] not supported", hashAlgorithm)
+	}
+
+	return computed, nil
+}
+
+// multihashCode returns the hash algorithm code of the given multihash (<code><length><digest>)
+func multihashCode(hash []byte) (uint, bool) {
+
+	code, n := binary.Uvarint(hash)
+	if n <= 0 {
+		return 0, false
+	}
+
+	length, m := binary.Uvarint(hash[n:])
+	if m <= 0 || uint64(len(hash)-n-m) != length {
+		return 0, false
 	}
 
-	return c.channelClient, nil
+	return uint(code), true
 }
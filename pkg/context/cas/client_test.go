@@ -7,49 +7,37 @@ SPDX-License-Identifier: Apache-2.0
 package cas
 
 import (
+	"crypto/sha256"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+	coreMocks "github.com/trustbloc/sidetree-core-go/pkg/mocks"
 
 	"github.com/trustbloc/sidetree-fabric/pkg/context/cas/mocks"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
-	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
-	fabMocks "github.com/hyperledger/fabric-sdk-go/pkg/fab/mocks"
-)
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
 
-const chID = "mychannel"
+	"github.com/trustbloc/sidetree-fabric/pkg/metrics"
+)
 
-func TestNew(t *testing.T) {
-	ctx := channelProvider(chID)
-	c := New(ctx)
-	require.NotNil(t, c)
-}
+const (
+	// sha256Code is the multihash code of the SHA2-256 algorithm
+	sha256Code = 18
 
-func TestGetClientError(t *testing.T) {
-	testErr := errors.New("provider error")
-	ctx := channelProviderWithError(testErr)
+	ccID = "sidetreetxn_cc"
+)
 
-	c := New(ctx)
+func TestNew(t *testing.T) {
+	c := New(mocks.NewMockTxnClient(), ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
 	require.NotNil(t, c)
-
-	content := []byte("content")
-	address, err := c.Write(content)
-	require.NotNil(t, err)
-	require.Empty(t, address)
-	require.Contains(t, err.Error(), testErr.Error())
-
-	payload, err := c.Read("address")
-	require.NotNil(t, err)
-	require.Nil(t, payload)
-	require.Contains(t, err.Error(), testErr.Error())
 }
 
 func TestWriteContent(t *testing.T) {
-	cas := New(channelProvider(chID))
-
-	cas.channelClient = mocks.NewMockChannelClient()
+	cas := New(mocks.NewMockTxnClient(), ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
 
 	content := []byte("content")
 	address, err := cas.Write(content)
@@ -62,47 +50,229 @@ func TestWriteContent(t *testing.T) {
 	require.Equal(t, content, read)
 }
 
+func TestWriteContent_Receipt(t *testing.T) {
+	cas := New(mocks.NewMockTxnClient(), ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	content := []byte("content")
+	address, receipt, err := cas.WriteWithReceipt(content)
+	require.Nil(t, err)
+	require.Equal(t, multihashAddress(t, content), address)
+	require.NotNil(t, receipt)
+	require.Equal(t, "txID", receipt.TxID)
+	require.Equal(t, uint64(1), receipt.BlockNumber)
+}
+
+func TestChaincodeID(t *testing.T) {
+	cc := mocks.NewMockTxnClient()
+
+	cas := New(cc, "othercc", coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	address, err := cas.Write([]byte("content"))
+	require.Nil(t, err)
+	require.Equal(t, "othercc", cc.Request.ChaincodeID)
+
+	_, err = New(cc, "othercc2", coreMocks.NewMockProtocolClient(), CacheConfig{}).Read(address)
+	require.Nil(t, err)
+	require.Equal(t, "othercc2", cc.Request.ChaincodeID)
+}
+
 func TestWriteContentError(t *testing.T) {
 
 	testErr := errors.New("channel error")
-	cc := mocks.NewMockChannelClient()
+	cc := mocks.NewMockTxnClient()
 	cc.Err = testErr
 
-	cas := New(channelProvider(chID))
-	cas.channelClient = cc
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	errs := testutil.ToFloat64(metrics.CASErrors.WithLabelValues(metrics.CASWrite))
 
 	content := []byte("content")
 	address, err := cas.Write(content)
 	require.NotNil(t, err)
 	require.Empty(t, address)
 	require.Contains(t, err.Error(), testErr.Error())
+	require.Equal(t, errs+1, testutil.ToFloat64(metrics.CASErrors.WithLabelValues(metrics.CASWrite)))
 }
 
 func TestReadContentError(t *testing.T) {
 
 	testErr := errors.New("channel error")
-	cc := mocks.NewMockChannelClient()
+	cc := mocks.NewMockTxnClient()
 	cc.Err = testErr
 
-	cas := New(channelProvider(chID))
-	cas.channelClient = cc
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	errs := testutil.ToFloat64(metrics.CASErrors.WithLabelValues(metrics.CASRead))
 
 	read, err := cas.Read("address")
 	require.NotNil(t, err)
 	require.Nil(t, read)
 	require.Contains(t, err.Error(), testErr.Error())
+	require.Equal(t, errs+1, testutil.ToFloat64(metrics.CASErrors.WithLabelValues(metrics.CASRead)))
 }
 
-func channelProvider(channelID string) context.ChannelProvider {
-	channelProvider := func() (context.Channel, error) {
-		return fabMocks.NewMockChannel(channelID)
-	}
-	return channelProvider
+func TestReadContent_IntegrityError(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	// a peer returns content which does not match the address
+	address := multihashAddress(t, []byte("content"))
+	cc.Put(address, []byte("other content"))
+
+	read, err := cas.Read(address)
+	require.NotNil(t, err)
+	require.Nil(t, read)
+
+	integrityErr, ok := err.(*IntegrityError)
+	require.True(t, ok)
+	require.Equal(t, address, integrityErr.Address)
+	require.Equal(t, multihashAddress(t, []byte("other content")), integrityErr.ComputedAddress)
+	require.Contains(t, err.Error(), "content integrity check failed")
 }
 
-func channelProviderWithError(err error) context.ChannelProvider {
-	channelProvider := func() (context.Channel, error) {
-		return nil, err
-	}
-	return channelProvider
+func TestReadContent_LegacyAddress(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	content := []byte("content")
+	hash := sha256.Sum256(content)
+	legacyAddress := docutil.EncodeToString(hash[:])
+	cc.Put(legacyAddress, content)
+
+	read, err := cas.Read(legacyAddress)
+	require.Nil(t, err)
+	require.Equal(t, content, read)
+
+	// the content is not cached by this client
+	cas = New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	cc.Put(legacyAddress, []byte("other content"))
+
+	read, err = cas.Read(legacyAddress)
+	require.NotNil(t, err)
+	require.Nil(t, read)
+
+	_, ok := err.(*IntegrityError)
+	require.True(t, ok)
+}
+
+func TestReadContent_InvalidAddress(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	address := docutil.EncodeToString([]byte("abc"))
+	cc.Put(address, []byte("content"))
+
+	read, err := cas.Read(address)
+	require.NotNil(t, err)
+	require.Nil(t, read)
+	require.Contains(t, err.Error(), "address is neither a multihash nor a SHA256 hash")
+
+	cc.Put("invalid address", []byte("content"))
+
+	read, err = cas.Read("invalid address")
+	require.NotNil(t, err)
+	require.Nil(t, read)
+	require.Contains(t, err.Error(), "invalid address [invalid address]")
+
+	// multihash with an unsupported hash algorithm
+	unsupported := docutil.EncodeToString([]byte{0x55, 0x02, 0x01, 0x02})
+	cc.Put(unsupported, []byte("content"))
+
+	read, err = cas.Read(unsupported)
+	require.NotNil(t, err)
+	require.Nil(t, read)
+	require.Contains(t, err.Error(), "hash algorithm [85] not supported")
+}
+
+func TestReadContent_NotFound(t *testing.T) {
+
+	cas := New(mocks.NewMockTxnClient(), ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	read, err := cas.Read(multihashAddress(t, []byte("content")))
+	require.NotNil(t, err)
+	require.Nil(t, read)
+	require.Equal(t, ErrContentNotFound, errors.Cause(err))
+}
+
+func TestContent_TransportError(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+	cc.Err = status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil)
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	read, err := cas.Read(multihashAddress(t, []byte("content")))
+	require.NotNil(t, err)
+	require.Nil(t, read)
+	require.Equal(t, ErrTransport, errors.Cause(err))
+	require.Contains(t, err.Error(), "connection failed")
+
+	address, err := cas.Write([]byte("content"))
+	require.NotNil(t, err)
+	require.Empty(t, address)
+	require.Equal(t, ErrTransport, errors.Cause(err))
+}
+
+func TestReadContent_Cache(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	content := []byte("content")
+	address, err := cas.Write(content)
+	require.Nil(t, err)
+
+	// the peer is not queried for content which was written
+	cc.Err = errors.New("channel error")
+
+	read, err := cas.Read(address)
+	require.Nil(t, err)
+	require.Equal(t, content, read)
+
+	// content which was read is cached
+	other := []byte("other content")
+	otherAddress := multihashAddress(t, other)
+	cc.Put(otherAddress, other)
+	cc.Err = nil
+
+	read, err = cas.Read(otherAddress)
+	require.Nil(t, err)
+	require.Equal(t, other, read)
+
+	cc.Err = errors.New("channel error")
+
+	read, err = cas.Read(otherAddress)
+	require.Nil(t, err)
+	require.Equal(t, other, read)
+}
+
+func TestCache(t *testing.T) {
+
+	cc := mocks.NewMockTxnClient()
+	cc.Err = errors.New("channel error")
+
+	cas := New(cc, ccID, coreMocks.NewMockProtocolClient(), CacheConfig{})
+
+	content := []byte("content")
+	address := multihashAddress(t, content)
+	cas.Cache(address, content)
+
+	read, err := cas.Read(address)
+	require.Nil(t, err)
+	require.Equal(t, content, read)
+}
+
+func multihashAddress(t *testing.T, content []byte) string {
+	hash, err := docutil.ComputeMultihash(sha256Code, content)
+	require.Nil(t, err)
+
+	return docutil.EncodeToString(hash)
 }
@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cas
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+)
+
+var (
+	// ErrContentNotFound is returned if there is no content at the requested address (e.g. the
+	// content has not been replicated to the peer yet)
+	ErrContentNotFound = errors.New("content not found")
+
+	// ErrBadRequest is returned if the chaincode rejected the request
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrTransport is returned if the peers could not be reached (e.g. the connection failed or timed out
+	// or the peers could not be discovered)
+	ErrTransport = errors.New("transport failure")
+)
+
+// mapError maps the given channel client error to ErrContentNotFound, ErrBadRequest or ErrTransport
+// (which are available as the cause of the returned error). Other errors are returned as is.
+func mapError(err error) error {
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	typedErr := typedError(s)
+	if typedErr == nil {
+		return err
+	}
+
+	return errors.Wrap(typedErr, err.Error())
+}
+
+// typedError returns the typed error of the given status. If the status holds the errors of multiple
+// peers then the typed error of the first peer which returned a typed error is returned.
+func typedError(s *status.Status) error {
+
+	switch s.Group {
+	case status.ChaincodeStatus, status.EndorserServerStatus:
+		// the code is the status of the chaincode response
+		switch {
+		case s.Code == http.StatusNotFound:
+			return ErrContentNotFound
+		case s.Code >= http.StatusBadRequest && s.Code < http.StatusInternalServerError:
+			return ErrBadRequest
+		}
+	case status.GRPCTransportStatus, status.HTTPTransportStatus, status.EndorserClientStatus, status.DiscoveryServerStatus:
+		return ErrTransport
+	case status.ClientStatus:
+		switch s.Code {
+		case status.MultipleErrors.ToInt32():
+			return firstTypedError(s.Details)
+		case status.Timeout.ToInt32(), status.NoPeersFound.ToInt32():
+			// the peers did not respond in time or none of the peers could be selected
+			return ErrTransport
+		}
+	}
+
+	return nil
+}
+
+func firstTypedError(details []interface{}) error {
+
+	for _, d := range details {
+		err, ok := d.(error)
+		if !ok {
+			continue
+		}
+
+		s, ok := status.FromError(err)
+		if !ok {
+			continue
+		}
+
+		if typedErr := typedError(s); typedErr != nil {
+			return typedErr
+		}
+	}
+
+	return nil
+}
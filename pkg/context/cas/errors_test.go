@@ -0,0 +1,99 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cas
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+)
+
+func TestMapError(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "chaincode not found",
+			err:      status.New(status.ChaincodeStatus, http.StatusNotFound, "content not found", nil),
+			expected: ErrContentNotFound,
+		},
+		{
+			name:     "endorser not found",
+			err:      status.New(status.EndorserServerStatus, http.StatusNotFound, "content not found", nil),
+			expected: ErrContentNotFound,
+		},
+		{
+			name:     "chaincode bad request",
+			err:      status.New(status.ChaincodeStatus, http.StatusBadRequest, "missing content address", nil),
+			expected: ErrBadRequest,
+		},
+		{
+			name:     "grpc transport",
+			err:      status.New(status.GRPCTransportStatus, 14, "unavailable", nil),
+			expected: ErrTransport,
+		},
+		{
+			name:     "endorser client",
+			err:      status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil),
+			expected: ErrTransport,
+		},
+		{
+			name:     "client timeout",
+			err:      status.New(status.ClientStatus, status.Timeout.ToInt32(), "timeout", nil),
+			expected: ErrTransport,
+		},
+		{
+			name:     "no peers found",
+			err:      status.New(status.ClientStatus, status.NoPeersFound.ToInt32(), "no peers found", nil),
+			expected: ErrTransport,
+		},
+		{
+			name:     "discovery",
+			err:      status.New(status.DiscoveryServerStatus, status.Unknown.ToInt32(), "access denied", nil),
+			expected: ErrTransport,
+		},
+		{
+			name: "multiple peers",
+			err: multi.Errors{
+				errors.New("other error"),
+				status.New(status.ChaincodeStatus, http.StatusNotFound, "content not found", nil),
+				status.New(status.EndorserClientStatus, status.ConnectionFailed.ToInt32(), "connection failed", nil),
+			},
+			expected: ErrContentNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := mapError(test.err)
+			require.Equal(t, test.expected, errors.Cause(err))
+			require.Contains(t, err.Error(), test.err.Error())
+		})
+	}
+}
+
+func TestMapError_Untyped(t *testing.T) {
+
+	errs := []error{
+		errors.New("some error"),
+		status.New(status.ChaincodeStatus, http.StatusInternalServerError, "failed to read content", nil),
+		status.New(status.ClientStatus, status.SignatureVerificationFailed.ToInt32(), "signature verification failed", nil),
+		multi.Errors{errors.New("some error"), errors.New("other error")},
+	}
+
+	for _, err := range errs {
+		require.Equal(t, err, mapError(err))
+	}
+}
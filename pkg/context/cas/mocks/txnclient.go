@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mocks
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+)
+
+// MockTxnClient mocks the transaction client
+type MockTxnClient struct {
+	Err error
+	// Request is the last executed or queried request
+	Request channel.Request
+	lock    sync.RWMutex
+	content map[string][]byte
+}
+
+// NewMockTxnClient returns mock transaction client
+func NewMockTxnClient() *MockTxnClient {
+	return &MockTxnClient{content: make(map[string][]byte)}
+}
+
+// Put stores the given content at the given address without computing the address
+func (cc *MockTxnClient) Put(address string, content []byte) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+
+	cc.content[address] = content
+}
+
+// Query mocks query
+func (cc *MockTxnClient) Query(request channel.Request) ([]byte, error) {
+	cc.Request = request
+
+	if cc.Err != nil {
+		return nil, cc.Err
+	}
+
+	cc.lock.RLock()
+	content, ok := cc.content[string(request.Args[0])]
+	cc.lock.RUnlock()
+
+	if !ok {
+		return nil, status.New(status.ChaincodeStatus, http.StatusNotFound, "content not found", nil)
+	}
+
+	return content, nil
+}
+
+// Execute mocks execute
+func (cc *MockTxnClient) Execute(request channel.Request) ([]byte, *txn.Receipt, error) {
+	cc.Request = request
+
+	if cc.Err != nil {
+		return nil, nil, cc.Err
+	}
+
+	hashAlgorithmBytes, ok := request.TransientMap["hashAlgorithm"]
+	if !ok {
+		return nil, nil, errors.New("missing hash algorithm")
+	}
+
+	hashAlgorithm, err := strconv.ParseUint(string(hashAlgorithmBytes), 10, 64)
+	if err != nil {
+		return nil, nil, errors.Errorf("invalid hash algorithm [%s]", hashAlgorithmBytes)
+	}
+
+	content := request.TransientMap["content"]
+
+	hash, err := docutil.ComputeMultihash(uint(hashAlgorithm), content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	address := docutil.EncodeToString(hash)
+	cc.Put(address, content)
+
+	return []byte(address), &txn.Receipt{TxID: "txID", BlockNumber: 1}, nil
+}
@@ -7,6 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package context
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/core"
 	sdkConfig "github.com/hyperledger/fabric-sdk-go/pkg/core/config"
@@ -17,39 +21,88 @@ import (
 	"github.com/spf13/viper"
 	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
 	"github.com/trustbloc/sidetree-core-go/pkg/batch"
-	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
 	"github.com/trustbloc/sidetree-core-go/pkg/processor"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/anchor"
 	"github.com/trustbloc/sidetree-fabric/pkg/context/blockchain"
 	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opqueue"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
 	"github.com/trustbloc/sidetree-fabric/pkg/context/protocol"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/store"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
+	"github.com/trustbloc/sidetree-fabric/pkg/observer"
 )
 
 const (
-	keyProtocolFile = "protocol.file"
-	keyConfigFile   = "config.file"
-
-	defaultConfigFile   = "config.yaml"
-	defaultProtocolFile = "protocol.json"
+	keyProtocolFile       = "protocol.file"
+	keyProtocolSource     = "protocol.source"
+	keyConfigFile         = "config.file"
+	keyOperationStorePath = "operationstore.path"
+	keyOperationQueuePath = "operationqueue.path"
+
+	defaultConfigFile         = "config.yaml"
+	defaultProtocolFile       = "protocol.json"
+	defaultOperationStorePath = "operationstore"
+	defaultOperationQueuePath = "operationqueue"
+	defaultProtocolSource     = protocolSourceFile
+
+	// protocolSourceFile loads the protocol versions from the protocol file
+	protocolSourceFile = "file"
+	// protocolSourceLedger loads the protocol versions from the ledger
+	protocolSourceLedger = "ledger"
+
+	// defaultChaincodeID is the ID of the Sidetree transaction chaincode if none is configured
+	defaultChaincodeID = "sidetreetxn_cc"
+	// defaultAnchorPrefix is the key prefix of anchor addresses if none is configured
+	// (must match the anchor prefix of the chaincode)
+	defaultAnchorPrefix = "sidetreetxn_"
 )
 
 var logger = logrus.New()
 
-// SidetreeContext implements 'Fabric' version of Sidetree node context
+// SidetreeContext implements 'Fabric' version of Sidetree node context for a DID namespace
 type SidetreeContext struct {
+	namespace            string
 	protocolClient       protocolApi.Client
 	casClient            batch.CASClient
+	casReader            *cas.Client
 	blockchainClient     batch.BlockchainClient
 	operationStoreClient processor.OperationStoreClient
+	operationQueue       *opqueue.Queue
+	operationStatus      *opstatus.Tracker
+	observer             *observer.Observer
+	txnClient            *txn.Client
+	anchorClient         *anchor.Client
+	chaincodeID          string
+	maxObserverLag       uint64
+	sdk                  *sharedSDK
+	closeOnce            sync.Once
 }
 
-// New creates new Sidetree context
-func New(cfg *viper.Viper) (*SidetreeContext, error) {
+type sdkCloser interface {
+	Close()
+}
 
-	pc, err := getProtocolClient(cfg)
-	if err != nil {
-		logger.Errorf("Failed to load protocol: %s", err.Error())
-		return nil, err
+// sharedSDK is the SDK shared by the contexts of all namespaces. It is closed once all contexts were closed.
+type sharedSDK struct {
+	sdk  sdkCloser
+	refs int32
+}
+
+func (s *sharedSDK) acquire() *sharedSDK {
+	atomic.AddInt32(&s.refs, 1)
+	return s
+}
+
+func (s *sharedSDK) release() {
+	if atomic.AddInt32(&s.refs, -1) == 0 {
+		logger.Info("Closing SDK")
+		s.sdk.Close()
 	}
+}
+
+// New creates a Sidetree context for each DID namespace served by the node
+func New(cfg *viper.Viper) ([]*SidetreeContext, error) {
 
 	configProvider := getConfigProvider(cfg)
 	sdk, err := fabsdk.New(configProvider)
@@ -64,20 +117,87 @@ func New(cfg *viper.Viper) (*SidetreeContext, error) {
 		return nil, err
 	}
 
-	chCtx := sdk.ChannelContext(sidetreeCfg.Channel, fabsdk.WithUser(sidetreeCfg.User))
-	logger.Debugf("Created channel context for %s with user %s", sidetreeCfg.Channel, sidetreeCfg.User)
+	nsCfgs, err := getNamespaceConfigs(cfg, sidetreeCfg)
+	if err != nil {
+		logger.Errorf("Invalid namespace configuration: %s", err.Error())
+		return nil, err
+	}
+
+	shared := &sharedSDK{sdk: sdk}
+
+	// the SDK is closed if no context can be created
+	shared.acquire()
+	defer shared.release()
+
+	var ctxs []*SidetreeContext
+	for _, nsCfg := range nsCfgs {
+		var ctx *SidetreeContext
+		ctx, err = newNamespaceContext(sdk, sidetreeCfg, nsCfg)
+		if err != nil {
+			closeContexts(ctxs)
+			return nil, err
+		}
 
-	return newSidetreeContext(chCtx, pc)
+		ctx.sdk = shared.acquire()
+		ctxs = append(ctxs, ctx)
+	}
+
+	return ctxs, nil
 }
 
-func getProtocolClient(cfg *viper.Viper) (*protocol.Client, error) {
+// newNamespaceContext creates the Sidetree context of the given namespace
+func newNamespaceContext(sdk *fabsdk.FabricSDK, sidetreeCfg *sidetreeConfig, nsCfg namespaceConfig) (*SidetreeContext, error) {
+
+	chCtx := sdk.ChannelContext(nsCfg.Channel, fabsdk.WithUser(nsCfg.User))
+	logger.Debugf("Created channel context for %s with user %s for namespace %s", nsCfg.Channel, nsCfg.User, nsCfg.Namespace)
+
+	tc := txn.New(chCtx, sidetreeCfg.CommitTimeout)
+
+	pc, err := getProtocolClient(nsCfg, chCtx, tc)
+	if err != nil {
+		logger.Errorf("Failed to load protocol of namespace %s: %s", nsCfg.Namespace, err.Error())
+		return nil, err
+	}
+
+	opStore, err := store.New(nsCfg.OperationStorePath)
+	if err != nil {
+		logger.Errorf("Failed to open operation store of namespace %s: %s", nsCfg.Namespace, err.Error())
+		return nil, err
+	}
+
+	opQueue, err := opqueue.New(nsCfg.OperationQueuePath)
+	if err != nil {
+		logger.Errorf("Failed to open operation queue of namespace %s: %s", nsCfg.Namespace, err.Error())
+
+		if e := opStore.Close(); e != nil {
+			logger.Warnf("Failed to close operation store of namespace %s: %s", nsCfg.Namespace, e)
+		}
 
-	protocolConfigFile := defaultProtocolFile
-	if cfg.IsSet(keyProtocolFile) {
-		protocolConfigFile = cfg.GetString(keyProtocolFile)
+		return nil, err
 	}
 
-	return protocol.New(protocolConfigFile)
+	return newSidetreeContext(chCtx, tc, pc, opStore, opQueue, sidetreeCfg, nsCfg)
+}
+
+// getProtocolClient returns the protocol client of the given namespace. The current protocol version
+// is determined by the block height of the channel as reported by the given transaction client.
+func getProtocolClient(nsCfg namespaceConfig, channelProvider context.ChannelProvider, tc *txn.Client) (*protocol.Client, error) {
+
+	switch nsCfg.ProtocolSource {
+	case protocolSourceFile:
+		return protocol.New(nsCfg.ProtocolFile, tc)
+	case protocolSourceLedger:
+		return protocol.NewFromLedger(channelProvider, nsCfg.ChaincodeID, tc)
+	default:
+		return nil, errors.Errorf("unsupported protocol source [%s]", nsCfg.ProtocolSource)
+	}
+}
+
+// closeContexts closes the given contexts
+func closeContexts(ctxs []*SidetreeContext) {
+	for _, ctx := range ctxs {
+		ctx.Close()
+	}
 }
 
 func getConfigProvider(cfg *viper.Viper) core.ConfigProvider {
@@ -108,27 +228,58 @@ func getSidetreeConfig(configProvider core.ConfigProvider) (*sidetreeConfig, err
 		return nil, err
 	}
 
+	if sidetreeCfg.ChaincodeID == "" {
+		sidetreeCfg.ChaincodeID = defaultChaincodeID
+	}
+
+	if sidetreeCfg.AnchorPrefix == "" {
+		sidetreeCfg.AnchorPrefix = defaultAnchorPrefix
+	}
+
+	if sidetreeCfg.MaxObserverLag == 0 {
+		sidetreeCfg.MaxObserverLag = defaultMaxObserverLag
+	}
+
 	return &sidetreeCfg, nil
 }
 
-// newSidetreeContext returns Sidetree node context
-func newSidetreeContext(channelProvider context.ChannelProvider, pc protocolApi.Client) (*SidetreeContext, error) {
+// newSidetreeContext returns Sidetree node context of the given namespace
+func newSidetreeContext(channelProvider context.ChannelProvider, tc *txn.Client, pc *protocol.Client, opStore *store.Store, opQueue *opqueue.Queue, sidetreeCfg *sidetreeConfig, nsCfg namespaceConfig) (*SidetreeContext, error) {
 
-	bc := blockchain.New(channelProvider)
+	casc := cas.New(tc, nsCfg.ChaincodeID, pc, sidetreeCfg.CASCache)
 
-	casc := cas.New(channelProvider)
+	tracker := opstatus.New(pc, opStore, sidetreeCfg.OperationStatus)
+
+	// the batch writer anchors batches in a single transaction through the anchor client which
+	// removes the operations of a batch from the operation queue once the batch is anchored
+	ac := anchor.New(tc, nsCfg.ChaincodeID, pc, casc, opQueue, tracker, sidetreeCfg.AnchorRetry)
+
+	observerCfg := observer.Config{ChaincodeID: nsCfg.ChaincodeID, AnchorPrefix: nsCfg.AnchorPrefix}
 
 	ctx := &SidetreeContext{
-		protocolClient:   pc,
-		casClient:        casc,
-		blockchainClient: bc,
-		// Mock store will be replaced with real store
-		operationStoreClient: mocks.NewMockOperationStore(nil),
+		namespace:            nsCfg.Namespace,
+		protocolClient:       pc,
+		casClient:            ac,
+		casReader:            casc,
+		blockchainClient:     ac,
+		operationStoreClient: opStore,
+		operationQueue:       opQueue,
+		operationStatus:      tracker,
+		observer:             observer.New(channelProvider, observerCfg, casc, opStore, pc),
+		txnClient:            tc,
+		anchorClient:         ac,
+		chaincodeID:          nsCfg.ChaincodeID,
+		maxObserverLag:       sidetreeCfg.MaxObserverLag,
 	}
 
 	return ctx, nil
 }
 
+// Namespace returns the DID namespace (e.g. did:sidetree:) of the context
+func (m *SidetreeContext) Namespace() string {
+	return m.namespace
+}
+
 // Protocol returns protocol client
 func (m *SidetreeContext) Protocol() protocolApi.Client {
 	return m.protocolClient
@@ -144,14 +295,82 @@ func (m *SidetreeContext) CAS() batch.CASClient {
 	return m.casClient
 }
 
+// CASReader returns the client which reads (cached) content from content addressable storage
+func (m *SidetreeContext) CASReader() *cas.Client {
+	return m.casReader
+}
+
 // OperationStore gets operation store client
 func (m *SidetreeContext) OperationStore() processor.OperationStoreClient {
 	return m.operationStoreClient
 }
 
+// OperationQueue returns the durable queue of the operations which are not yet anchored
+func (m *SidetreeContext) OperationQueue() *opqueue.Queue {
+	return m.operationQueue
+}
+
+// OperationStatus returns the tracker of the status of the operations which were submitted to the node
+func (m *SidetreeContext) OperationStatus() *opstatus.Tracker {
+	return m.operationStatus
+}
+
+// Observer returns the observer which feeds anchored operations into the operation store
+func (m *SidetreeContext) Observer() *observer.Observer {
+	return m.observer
+}
+
+// SubmittedOperations returns the number of operations of the batches which were submitted
+// for anchoring (whether or not they were anchored)
+func (m *SidetreeContext) SubmittedOperations() uint64 {
+	return m.anchorClient.SubmittedOperations()
+}
+
+// Close stops the observer and closes the operation store and queue of the context. The SDK is closed
+// once the contexts of all namespaces are closed. The batch writer must be stopped before.
+func (m *SidetreeContext) Close() {
+	m.closeOnce.Do(func() {
+		m.observer.Stop()
+
+		if s, ok := m.operationStoreClient.(*store.Store); ok {
+			if err := s.Close(); err != nil {
+				logger.Warnf("Failed to close operation store of namespace %s: %s", m.namespace, err)
+			}
+		}
+
+		if err := m.operationQueue.Close(); err != nil {
+			logger.Warnf("Failed to close operation queue of namespace %s: %s", m.namespace, err)
+		}
+
+		if m.sdk != nil {
+			m.sdk.release()
+		}
+
+		logger.Infof("Closed context of namespace %s", m.namespace)
+	})
+}
+
 //sidetreeConfig defines 'fabric' channel used for recording Sidetree transaction
 // and channel user for performing transactions on that channel
 type sidetreeConfig struct {
 	Channel string
 	User    string
+	// ChaincodeID is the ID of the Sidetree transaction chaincode
+	ChaincodeID string
+	// AnchorPrefix is the key prefix under which the chaincode records anchor addresses
+	AnchorPrefix string
+	// CASCache holds the limits of the CAS content cache
+	CASCache cas.CacheConfig
+	// CommitTimeout is the time to wait for a transaction to be committed
+	CommitTimeout time.Duration
+	// AnchorRetry holds the retry policy for anchoring batches
+	AnchorRetry blockchain.RetryConfig
+	// OperationStatus holds the limits of the operation status tracker
+	OperationStatus opstatus.Config
+	// MaxObserverLag is the number of blocks which the observer may fall behind the channel
+	// before the node is reported as not ready
+	MaxObserverLag uint64
+	// Namespaces declares the DID namespaces served by the node. If none are declared then
+	// the node serves the default namespace on the channel above.
+	Namespaces []namespaceConfig
 }
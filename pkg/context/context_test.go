@@ -7,12 +7,24 @@ SPDX-License-Identifier: Apache-2.0
 package context
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
-	"github.com/trustbloc/sidetree-core-go/pkg/mocks"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/blockchain"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opqueue"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/opstatus"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/protocol"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/store"
+	"github.com/trustbloc/sidetree-fabric/pkg/context/txn"
 
 	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	sdkConfig "github.com/hyperledger/fabric-sdk-go/pkg/core/config"
 	"github.com/stretchr/testify/require"
 
 	fabMocks "github.com/hyperledger/fabric-sdk-go/pkg/fab/mocks"
@@ -24,19 +36,97 @@ const (
 )
 
 func TestNew(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	queueDir, queueCleanup := tempDir(t)
+	defer queueCleanup()
+
 	config := viper.New()
 
 	config.Set(keyConfigFile, sdkConfigFile)
 	config.Set(keyProtocolFile, protocolConfigFile)
+	config.Set(keyOperationStorePath, dir)
+	config.Set(keyOperationQueuePath, queueDir)
 
-	sctx, err := New(config)
+	ctxs, err := New(config)
 	require.Nil(t, err)
+	require.Len(t, ctxs, 1)
 
+	sctx := ctxs[0]
+	require.Equal(t, defaultNamespace, sctx.Namespace())
 	require.NotNil(t, sctx.Protocol())
 	require.NotNil(t, sctx.CAS())
+	require.NotNil(t, sctx.CASReader())
 	require.NotNil(t, sctx.Blockchain())
 	require.NotNil(t, sctx.OperationStore())
+	require.NotNil(t, sctx.OperationQueue())
+	require.NotNil(t, sctx.OperationStatus())
+	require.NotNil(t, sctx.Observer())
+
+	sctx.Close()
+}
+
+func TestNew_ConfiguredNamespace(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	queueDir, queueCleanup := tempDir(t)
+	defer queueCleanup()
+
+	config := viper.New()
+
+	config.Set(keyConfigFile, sdkConfigFile)
+	config.Set(keyProtocolFile, protocolConfigFile)
+	config.Set(keyOperationStorePath, dir)
+	config.Set(keyOperationQueuePath, queueDir)
+	config.Set(keyDIDNamespace, "did:example:")
+
+	ctxs, err := New(config)
+	require.Nil(t, err)
+	require.Len(t, ctxs, 1)
+	require.Equal(t, "did:example:", ctxs[0].Namespace())
+	ctxs[0].Close()
+
+	config.Set(keyDIDNamespace, "example")
+
+	ctxs, err = New(config)
+	require.NotNil(t, err)
+	require.Nil(t, ctxs)
+	require.Contains(t, err.Error(), "invalid namespace [example]")
+}
+
+func TestNew_Namespaces(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	queueDir, queueCleanup := tempDir(t)
+	defer queueCleanup()
+
+	config := viper.New()
+
+	config.Set(keyConfigFile, "./testdata/config-namespaces.yaml")
+	config.Set(keyProtocolFile, protocolConfigFile)
+	config.Set(keyOperationStorePath, dir)
+	config.Set(keyOperationQueuePath, queueDir)
+
+	ctxs, err := New(config)
+	require.Nil(t, err)
+	require.Len(t, ctxs, 2)
+
+	require.Equal(t, "did:sidetree:", ctxs[0].Namespace())
+	require.Equal(t, "did:sidetree:test:", ctxs[1].Namespace())
+
+	// each namespace has its own operation store
+	require.False(t, ctxs[0].OperationStore() == ctxs[1].OperationStore())
+	require.DirExists(t, filepath.Join(dir, "did_sidetree"))
+	require.DirExists(t, filepath.Join(dir, "did_sidetree_test"))
 
+	// and its own operation queue
+	require.DirExists(t, filepath.Join(queueDir, "did_sidetree"))
+	require.DirExists(t, filepath.Join(queueDir, "did_sidetree_test"))
+
+	closeContexts(ctxs)
 }
 
 func TestNewSDKConfigError(t *testing.T) {
@@ -45,9 +135,9 @@ func TestNewSDKConfigError(t *testing.T) {
 	config.Set(keyConfigFile, "./invalid/config.yaml")
 	config.Set(keyProtocolFile, protocolConfigFile)
 
-	sctx, err := New(config)
+	ctxs, err := New(config)
 	require.NotNil(t, err)
-	require.Nil(t, sctx)
+	require.Nil(t, ctxs)
 	require.Contains(t, err.Error(), "failed to initialize configuration")
 
 }
@@ -58,9 +148,9 @@ func TestSidetreeConfigError(t *testing.T) {
 	config.Set(keyConfigFile, "./testdata/config-nosidetree.yaml")
 	config.Set(keyProtocolFile, protocolConfigFile)
 
-	sctx, err := New(config)
+	ctxs, err := New(config)
 	require.NotNil(t, err)
-	require.Nil(t, sctx)
+	require.Nil(t, ctxs)
 	require.Contains(t, err.Error(), "sidetree configuration key not found")
 
 }
@@ -71,29 +161,199 @@ func TestNewProtocolError(t *testing.T) {
 	config.Set(keyConfigFile, sdkConfigFile)
 	config.Set(keyProtocolFile, "./invalid/protocol.json")
 
-	sctx, err := New(config)
+	ctxs, err := New(config)
 	require.NotNil(t, err)
-	require.Nil(t, sctx)
+	require.Nil(t, ctxs)
 	require.Contains(t, err.Error(), "no such file or directory")
 
 }
 
+func TestNewProtocolSourceError(t *testing.T) {
+	config := viper.New()
+
+	config.Set(keyConfigFile, sdkConfigFile)
+	config.Set(keyProtocolSource, "other")
+
+	ctxs, err := New(config)
+	require.NotNil(t, err)
+	require.Nil(t, ctxs)
+	require.Contains(t, err.Error(), "unsupported protocol source [other]")
+}
+
+func TestGetProtocolClientFromLedger(t *testing.T) {
+	testErr := errors.New("provider error")
+	nsCfg := namespaceConfig{ProtocolSource: protocolSourceLedger, ChaincodeID: defaultChaincodeID}
+
+	chp := func() (context.Channel, error) { return nil, testErr }
+
+	pc, err := getProtocolClient(nsCfg, chp, txn.New(chp, 0))
+	require.NotNil(t, err)
+	require.Nil(t, pc)
+	require.Contains(t, err.Error(), testErr.Error())
+}
+
+func TestNewOperationStoreError(t *testing.T) {
+	f, err := ioutil.TempFile("", "operationstore")
+	require.Nil(t, err)
+	defer func() { require.Nil(t, os.Remove(f.Name())) }()
+
+	config := viper.New()
+
+	config.Set(keyConfigFile, sdkConfigFile)
+	config.Set(keyProtocolFile, protocolConfigFile)
+	config.Set(keyOperationStorePath, f.Name())
+
+	ctxs, err := New(config)
+	require.NotNil(t, err)
+	require.Nil(t, ctxs)
+	require.Contains(t, err.Error(), "failed to open operation store")
+
+}
+
+func TestNewOperationQueueError(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	f, err := ioutil.TempFile("", "operationqueue")
+	require.Nil(t, err)
+	defer func() { require.Nil(t, os.Remove(f.Name())) }()
+
+	config := viper.New()
+
+	config.Set(keyConfigFile, sdkConfigFile)
+	config.Set(keyProtocolFile, protocolConfigFile)
+	config.Set(keyOperationStorePath, dir)
+	config.Set(keyOperationQueuePath, f.Name())
+
+	ctxs, err := New(config)
+	require.NotNil(t, err)
+	require.Nil(t, ctxs)
+	require.Contains(t, err.Error(), "failed to open operation queue")
+
+	// the operation store was closed
+	opStore, err := store.New(dir)
+	require.Nil(t, err)
+	require.Nil(t, opStore.Close())
+}
+
 func TestNewSidetreeContext(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	opStore, err := store.New(filepath.Join(dir, "store"))
+	require.Nil(t, err)
+	defer func() { require.Nil(t, opStore.Close()) }()
+
+	opQueue, err := opqueue.New(filepath.Join(dir, "queue"))
+	require.Nil(t, err)
+	defer func() { require.Nil(t, opQueue.Close()) }()
+
 	ctx := mockChannelProvider("mychannel")
-	sctx, err := newSidetreeContext(ctx, mocks.NewMockProtocolClient())
+	tc := txn.New(ctx, 0)
+
+	pc, err := protocol.New(protocolConfigFile, tc)
+	require.Nil(t, err)
+
+	sctx, err := newSidetreeContext(ctx, tc, pc, opStore, opQueue, &sidetreeConfig{}, namespaceConfig{Namespace: defaultNamespace, ChaincodeID: defaultChaincodeID, AnchorPrefix: defaultAnchorPrefix})
 	require.Nil(t, err)
 	require.NotNil(t, sctx)
 
+	require.Equal(t, defaultNamespace, sctx.Namespace())
 	require.NotNil(t, sctx.Protocol())
 	require.NotNil(t, sctx.CAS())
+	require.NotNil(t, sctx.CASReader())
 	require.NotNil(t, sctx.Blockchain())
 	require.NotNil(t, sctx.OperationStore())
+	require.NotNil(t, sctx.OperationQueue())
+	require.NotNil(t, sctx.OperationStatus())
+	require.NotNil(t, sctx.Observer())
 
 }
 
+func TestSidetreeContext_Close(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	sdk := &mockSDK{}
+	shared := &sharedSDK{sdk: sdk}
+
+	ctx1 := newTestContext(t, filepath.Join(dir, "ctx1"), shared.acquire())
+	ctx2 := newTestContext(t, filepath.Join(dir, "ctx2"), shared.acquire())
+
+	ctx1.Close()
+	require.Equal(t, 0, sdk.closed)
+
+	// closing a context twice does not release the SDK twice
+	ctx1.Close()
+	require.Equal(t, 0, sdk.closed)
+
+	// the SDK is closed with the last context
+	ctx2.Close()
+	require.Equal(t, 1, sdk.closed)
+
+	// the operation store and queue are closed
+	_, _, err := ctx2.OperationStore().(*store.Store).LastBlockNum()
+	require.NotNil(t, err)
+
+	_, err = ctx2.OperationQueue().Operations()
+	require.NotNil(t, err)
+}
+
+func TestGetSidetreeConfig(t *testing.T) {
+	cfg, err := getSidetreeConfig(sdkConfig.FromFile(sdkConfigFile))
+	require.Nil(t, err)
+	require.Equal(t, "mychannel", cfg.Channel)
+	require.Equal(t, "User1", cfg.User)
+	require.Equal(t, "sidetreetxn_cc", cfg.ChaincodeID)
+	require.Equal(t, "sidetreetxn_", cfg.AnchorPrefix)
+	require.Equal(t, cas.CacheConfig{Size: 1000, MaxBytes: 104857600}, cfg.CASCache)
+	require.Equal(t, 30*time.Second, cfg.CommitTimeout)
+	require.Equal(t, blockchain.RetryConfig{MaxAttempts: 5, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second, BackoffFactor: 2}, cfg.AnchorRetry)
+	require.Equal(t, uint64(10), cfg.MaxObserverLag)
+	require.Equal(t, opstatus.Config{Size: 10000}, cfg.OperationStatus)
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sidetreecontext")
+	require.Nil(t, err)
+
+	return dir, func() {
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
+
 func mockChannelProvider(channelID string) context.ChannelProvider {
 	channelProvider := func() (context.Channel, error) {
 		return fabMocks.NewMockChannel(channelID)
 	}
 	return channelProvider
 }
+
+func newTestContext(t *testing.T, path string, sdk *sharedSDK) *SidetreeContext {
+	opStore, err := store.New(filepath.Join(path, "store"))
+	require.Nil(t, err)
+
+	opQueue, err := opqueue.New(filepath.Join(path, "queue"))
+	require.Nil(t, err)
+
+	chp := mockChannelProvider("mychannel")
+	tc := txn.New(chp, 0)
+
+	pc, err := protocol.New(protocolConfigFile, tc)
+	require.Nil(t, err)
+
+	sctx, err := newSidetreeContext(chp, tc, pc, opStore, opQueue, &sidetreeConfig{}, namespaceConfig{Namespace: defaultNamespace})
+	require.Nil(t, err)
+
+	sctx.sdk = sdk
+
+	return sctx
+}
+
+type mockSDK struct {
+	closed int
+}
+
+func (m *mockSDK) Close() {
+	m.closed++
+}
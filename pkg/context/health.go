@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package context
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/pkg/errors"
+)
+
+const (
+	// warmupFcn is the chaincode function which is invoked to check that the chaincode is reachable
+	warmupFcn = "warmup"
+
+	// defaultMaxObserverLag is the number of blocks which the observer may fall behind the channel
+	// before the node is reported as not ready
+	defaultMaxObserverLag = 10
+)
+
+type ledgerQuerier interface {
+	Query(request channel.Request) ([]byte, error)
+	BlockHeight() (uint64, error)
+}
+
+type observerStatus interface {
+	Running() bool
+	Lag(height uint64) (uint64, error)
+}
+
+// CheckChannel checks that the channel of the namespace can be queried
+func (m *SidetreeContext) CheckChannel() error {
+	return checkChannel(m.txnClient)
+}
+
+// CheckChaincode checks that the Sidetree transaction chaincode of the namespace can be invoked
+func (m *SidetreeContext) CheckChaincode() error {
+	return checkChaincode(m.txnClient, m.chaincodeID)
+}
+
+// CheckObserver checks that the observer of the namespace is running and does not lag behind the channel
+func (m *SidetreeContext) CheckObserver() error {
+	return checkObserver(m.txnClient, m.observer, m.maxObserverLag)
+}
+
+func checkChannel(lq ledgerQuerier) error {
+
+	_, err := lq.BlockHeight()
+	if err != nil {
+		return errors.WithMessage(err, "failed to query channel")
+	}
+
+	return nil
+}
+
+func checkChaincode(lq ledgerQuerier, ccID string) error {
+
+	_, err := lq.Query(channel.Request{ChaincodeID: ccID, Fcn: warmupFcn})
+	if err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("failed to invoke chaincode [%s]", ccID))
+	}
+
+	return nil
+}
+
+func checkObserver(lq ledgerQuerier, o observerStatus, maxLag uint64) error {
+
+	if !o.Running() {
+		return errors.New("observer is not running")
+	}
+
+	height, err := lq.BlockHeight()
+	if err != nil {
+		return errors.WithMessage(err, "failed to query channel")
+	}
+
+	lag, err := o.Lag(height)
+	if err != nil {
+		return err
+	}
+
+	if lag > maxLag {
+		return errors.Errorf("observer is %d blocks behind the channel (maximum %d)", lag, maxLag)
+	}
+
+	return nil
+}
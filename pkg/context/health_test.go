@@ -0,0 +1,101 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package context
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckChannel(t *testing.T) {
+	lq := &mockLedgerQuerier{height: 10}
+	require.Nil(t, checkChannel(lq))
+
+	lq.heightErr = errors.New("connection failed")
+
+	err := checkChannel(lq)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to query channel: connection failed")
+}
+
+func TestCheckChaincode(t *testing.T) {
+	lq := &mockLedgerQuerier{}
+	require.Nil(t, checkChaincode(lq, defaultChaincodeID))
+	require.Equal(t, defaultChaincodeID, lq.request.ChaincodeID)
+	require.Equal(t, warmupFcn, lq.request.Fcn)
+
+	lq.queryErr = errors.New("chaincode not found")
+
+	err := checkChaincode(lq, defaultChaincodeID)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to invoke chaincode [sidetreetxn_cc]: chaincode not found")
+}
+
+func TestCheckObserver(t *testing.T) {
+	lq := &mockLedgerQuerier{height: 20}
+	o := &mockObserver{running: true, lag: 10}
+
+	require.Nil(t, checkObserver(lq, o, 10))
+	require.Equal(t, uint64(20), o.height)
+
+	err := checkObserver(lq, o, 9)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "observer is 10 blocks behind the channel (maximum 9)")
+
+	o.lagErr = errors.New("store error")
+
+	err = checkObserver(lq, o, 10)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "store error")
+
+	lq.heightErr = errors.New("connection failed")
+
+	err = checkObserver(lq, o, 10)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "connection failed")
+
+	o.running = false
+
+	err = checkObserver(lq, o, 10)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "observer is not running")
+}
+
+type mockLedgerQuerier struct {
+	request   channel.Request
+	queryErr  error
+	height    uint64
+	heightErr error
+}
+
+func (m *mockLedgerQuerier) Query(request channel.Request) ([]byte, error) {
+	m.request = request
+	return nil, m.queryErr
+}
+
+func (m *mockLedgerQuerier) BlockHeight() (uint64, error) {
+	return m.height, m.heightErr
+}
+
+type mockObserver struct {
+	running bool
+	height  uint64
+	lag     uint64
+	lagErr  error
+}
+
+func (m *mockObserver) Running() bool {
+	return m.running
+}
+
+func (m *mockObserver) Lag(height uint64) (uint64, error) {
+	m.height = height
+	return m.lag, m.lagErr
+}
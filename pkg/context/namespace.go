@@ -0,0 +1,202 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package context
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	// keyDIDNamespace is the DID namespace of the node if the configuration does not declare any namespaces
+	// (environment variable SIDETREE_NODE_DID_NAMESPACE)
+	keyDIDNamespace = "did.namespace"
+
+	// defaultNamespace is the DID namespace of the node if neither the namespace nor namespaces are configured
+	defaultNamespace = "did:sidetree:"
+)
+
+// namespaceRegex matches DID namespaces of the form did:<method>: (optionally followed by sub-namespaces
+// such as did:<method>:test:). The method name consists of lowercase letters and digits.
+var namespaceRegex = regexp.MustCompile(`^did:[a-z0-9]+:([A-Za-z0-9._-]+:)*$`)
+
+// namespaceConfig maps a DID namespace to the channel, identity, chaincode, protocol and operation store
+// of its Sidetree deployment. Fields which are not set are inherited from the 'sidetree' configuration section
+// (or the node configuration for the protocol, operation store and operation queue).
+type namespaceConfig struct {
+	Namespace          string
+	Channel            string
+	User               string
+	ChaincodeID        string
+	AnchorPrefix       string
+	ProtocolSource     string
+	ProtocolFile       string
+	OperationStorePath string
+	OperationQueuePath string
+}
+
+// getNamespaceConfigs returns the configuration of each DID namespace served by the node. If no namespaces are
+// declared then the node serves the default namespace with the channel and chaincode of the 'sidetree' section.
+func getNamespaceConfigs(cfg *viper.Viper, sidetreeCfg *sidetreeConfig) ([]namespaceConfig, error) {
+
+	storePath := defaultOperationStorePath
+	if cfg.IsSet(keyOperationStorePath) {
+		storePath = cfg.GetString(keyOperationStorePath)
+	}
+
+	queuePath := defaultOperationQueuePath
+	if cfg.IsSet(keyOperationQueuePath) {
+		queuePath = cfg.GetString(keyOperationQueuePath)
+	}
+
+	if len(sidetreeCfg.Namespaces) == 0 {
+		namespace := defaultNamespace
+		if cfg.IsSet(keyDIDNamespace) {
+			namespace = cfg.GetString(keyDIDNamespace)
+		}
+
+		if err := validateNamespace(namespace); err != nil {
+			return nil, err
+		}
+
+		nsCfg := namespaceConfig{Namespace: namespace, OperationStorePath: storePath, OperationQueuePath: queuePath}
+		inheritNamespaceConfig(&nsCfg, cfg, sidetreeCfg)
+
+		if err := checkPaths(nsCfg); err != nil {
+			return nil, err
+		}
+
+		return []namespaceConfig{nsCfg}, nil
+	}
+
+	if cfg.IsSet(keyDIDNamespace) {
+		return nil, errors.Errorf("%s must not be set if namespaces are declared in the sidetree configuration", keyDIDNamespace)
+	}
+
+	var nsCfgs []namespaceConfig
+	for _, nsCfg := range sidetreeCfg.Namespaces {
+		if err := validateNamespace(nsCfg.Namespace); err != nil {
+			return nil, err
+		}
+
+		// each namespace has its own operation store and queue
+		if nsCfg.OperationStorePath == "" {
+			nsCfg.OperationStorePath = filepath.Join(storePath, storeDirName(nsCfg.Namespace))
+		}
+
+		if nsCfg.OperationQueuePath == "" {
+			nsCfg.OperationQueuePath = filepath.Join(queuePath, storeDirName(nsCfg.Namespace))
+		}
+
+		inheritNamespaceConfig(&nsCfg, cfg, sidetreeCfg)
+
+		if err := checkPaths(nsCfg); err != nil {
+			return nil, err
+		}
+
+		if err := checkUnique(nsCfgs, nsCfg); err != nil {
+			return nil, err
+		}
+
+		nsCfgs = append(nsCfgs, nsCfg)
+	}
+
+	return nsCfgs, nil
+}
+
+func inheritNamespaceConfig(nsCfg *namespaceConfig, cfg *viper.Viper, sidetreeCfg *sidetreeConfig) {
+
+	if nsCfg.Channel == "" {
+		nsCfg.Channel = sidetreeCfg.Channel
+	}
+
+	if nsCfg.User == "" {
+		nsCfg.User = sidetreeCfg.User
+	}
+
+	if nsCfg.ChaincodeID == "" {
+		nsCfg.ChaincodeID = sidetreeCfg.ChaincodeID
+	}
+
+	if nsCfg.AnchorPrefix == "" {
+		nsCfg.AnchorPrefix = sidetreeCfg.AnchorPrefix
+	}
+
+	if nsCfg.ProtocolSource == "" {
+		nsCfg.ProtocolSource = defaultProtocolSource
+		if cfg.IsSet(keyProtocolSource) {
+			nsCfg.ProtocolSource = cfg.GetString(keyProtocolSource)
+		}
+	}
+
+	if nsCfg.ProtocolFile == "" {
+		nsCfg.ProtocolFile = defaultProtocolFile
+		if cfg.IsSet(keyProtocolFile) {
+			nsCfg.ProtocolFile = cfg.GetString(keyProtocolFile)
+		}
+	}
+}
+
+// validateNamespace checks that the given namespace is a DID method (optionally with sub-namespaces)
+// followed by a colon, so that a DID is the namespace followed by the unique suffix
+func validateNamespace(namespace string) error {
+
+	if namespace == "" {
+		return errors.New("namespace is required")
+	}
+
+	if !namespaceRegex.MatchString(namespace) {
+		return errors.Errorf("invalid namespace [%s]: expecting did:<method>: where the method consists of lowercase letters and digits", namespace)
+	}
+
+	return nil
+}
+
+// checkUnique checks that the given namespace does not clash with the namespaces which were already configured
+func checkUnique(nsCfgs []namespaceConfig, nsCfg namespaceConfig) error {
+
+	for _, other := range nsCfgs {
+		if other.Namespace == nsCfg.Namespace {
+			return errors.Errorf("duplicate namespace [%s]", nsCfg.Namespace)
+		}
+
+		if filepath.Clean(other.OperationStorePath) == filepath.Clean(nsCfg.OperationStorePath) {
+			return errors.Errorf("namespaces [%s] and [%s] use the same operation store", other.Namespace, nsCfg.Namespace)
+		}
+
+		if filepath.Clean(other.OperationQueuePath) == filepath.Clean(nsCfg.OperationQueuePath) {
+			return errors.Errorf("namespaces [%s] and [%s] use the same operation queue", other.Namespace, nsCfg.Namespace)
+		}
+
+		// the chaincode records all anchors under the anchor prefix of its own configuration, so the anchors
+		// of both namespaces would be mixed up even with different prefixes
+		if other.Channel == nsCfg.Channel && other.ChaincodeID == nsCfg.ChaincodeID {
+			return errors.Errorf("namespaces [%s] and [%s] use the same channel and chaincode", other.Namespace, nsCfg.Namespace)
+		}
+	}
+
+	return nil
+}
+
+// checkPaths checks that the operation store and queue of the given namespace are not in the same directory
+func checkPaths(nsCfg namespaceConfig) error {
+
+	if filepath.Clean(nsCfg.OperationStorePath) == filepath.Clean(nsCfg.OperationQueuePath) {
+		return errors.Errorf("the operation store and operation queue of namespace [%s] must not be in the same directory", nsCfg.Namespace)
+	}
+
+	return nil
+}
+
+// storeDirName returns the name of the operation store directory of the given namespace (e.g. did_sidetree for did:sidetree:)
+func storeDirName(namespace string) string {
+	return strings.Trim(strings.Replace(namespace, ":", "_", -1), "_")
+}
@@ -0,0 +1,228 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package context
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNamespaceConfigs_Default(t *testing.T) {
+	config := viper.New()
+	config.Set(keyOperationStorePath, "store")
+	config.Set(keyProtocolFile, protocolConfigFile)
+
+	nsCfgs, err := getNamespaceConfigs(config, newTestSidetreeConfig())
+	require.Nil(t, err)
+	require.Equal(t, []namespaceConfig{{
+		Namespace:          defaultNamespace,
+		Channel:            "mychannel",
+		User:               "User1",
+		ChaincodeID:        defaultChaincodeID,
+		AnchorPrefix:       defaultAnchorPrefix,
+		ProtocolSource:     protocolSourceFile,
+		ProtocolFile:       protocolConfigFile,
+		OperationStorePath: "store",
+		OperationQueuePath: defaultOperationQueuePath,
+	}}, nsCfgs)
+}
+
+func TestGetNamespaceConfigs_ConfiguredNamespace(t *testing.T) {
+	config := viper.New()
+	config.Set(keyDIDNamespace, "did:example:")
+
+	nsCfgs, err := getNamespaceConfigs(config, newTestSidetreeConfig())
+	require.Nil(t, err)
+	require.Len(t, nsCfgs, 1)
+	require.Equal(t, "did:example:", nsCfgs[0].Namespace)
+
+	config.Set(keyDIDNamespace, "did:Example")
+
+	nsCfgs, err = getNamespaceConfigs(config, newTestSidetreeConfig())
+	require.NotNil(t, err)
+	require.Nil(t, nsCfgs)
+	require.Contains(t, err.Error(), "invalid namespace [did:Example]")
+
+	// the namespace cannot be combined with declared namespaces
+	config.Set(keyDIDNamespace, "did:example:")
+
+	sidetreeCfg := newTestSidetreeConfig()
+	sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:"}}
+
+	nsCfgs, err = getNamespaceConfigs(config, sidetreeCfg)
+	require.NotNil(t, err)
+	require.Nil(t, nsCfgs)
+	require.Contains(t, err.Error(), "did.namespace must not be set if namespaces are declared")
+}
+
+func TestValidateNamespace(t *testing.T) {
+	for _, namespace := range []string{"did:sidetree:", "did:sidetree:test:", "did:example1:", "did:trustbloc:testnet.1:"} {
+		require.Nil(t, validateNamespace(namespace), namespace)
+	}
+
+	for _, namespace := range []string{"did:sidetree", "sidetree:", "did::", "did:Sidetree:", "did:side tree:", "did:sidetree:test"} {
+		err := validateNamespace(namespace)
+		require.NotNil(t, err, namespace)
+		require.Contains(t, err.Error(), "invalid namespace")
+	}
+
+	err := validateNamespace("")
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "namespace is required")
+}
+
+func TestGetNamespaceConfigs(t *testing.T) {
+	config := viper.New()
+	config.Set(keyOperationStorePath, "store")
+	config.Set(keyOperationQueuePath, "queue")
+	config.Set(keyProtocolSource, protocolSourceLedger)
+
+	sidetreeCfg := newTestSidetreeConfig()
+	sidetreeCfg.Namespaces = []namespaceConfig{
+		{Namespace: "did:sidetree:"},
+		{
+			Namespace:          "did:sidetree:test:",
+			Channel:            "testchannel",
+			User:               "User2",
+			ChaincodeID:        "testcc",
+			AnchorPrefix:       "test_",
+			ProtocolSource:     protocolSourceFile,
+			ProtocolFile:       "test.json",
+			OperationStorePath: "teststore",
+			OperationQueuePath: "testqueue",
+		},
+	}
+
+	nsCfgs, err := getNamespaceConfigs(config, sidetreeCfg)
+	require.Nil(t, err)
+	require.Len(t, nsCfgs, 2)
+
+	require.Equal(t, namespaceConfig{
+		Namespace:          "did:sidetree:",
+		Channel:            "mychannel",
+		User:               "User1",
+		ChaincodeID:        defaultChaincodeID,
+		AnchorPrefix:       defaultAnchorPrefix,
+		ProtocolSource:     protocolSourceLedger,
+		ProtocolFile:       defaultProtocolFile,
+		OperationStorePath: filepath.Join("store", "did_sidetree"),
+		OperationQueuePath: filepath.Join("queue", "did_sidetree"),
+	}, nsCfgs[0])
+
+	// configured values are not overridden
+	require.Equal(t, sidetreeCfg.Namespaces[1], nsCfgs[1])
+}
+
+func TestGetNamespaceConfigs_Error(t *testing.T) {
+	config := viper.New()
+
+	t.Run("missing namespace", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Channel: "mychannel"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "namespace is required")
+	})
+
+	t.Run("invalid namespace", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "sidetree"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "invalid namespace [sidetree]")
+	})
+
+	t.Run("duplicate namespace", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:"}, {Namespace: "did:sidetree:", Channel: "other"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "duplicate namespace [did:sidetree:]")
+	})
+
+	t.Run("same operation store", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{
+			{Namespace: "did:sidetree:", OperationStorePath: "store"},
+			{Namespace: "did:sidetree:test:", Channel: "other", OperationStorePath: "./store"},
+		}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "use the same operation store")
+	})
+
+	t.Run("same operation queue", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{
+			{Namespace: "did:sidetree:", OperationQueuePath: "queue"},
+			{Namespace: "did:sidetree:test:", Channel: "other", OperationQueuePath: "./queue"},
+		}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "use the same operation queue")
+	})
+
+	t.Run("operation store and queue in the same directory", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:", OperationStorePath: "data", OperationQueuePath: "./data"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "the operation store and operation queue of namespace [did:sidetree:] must not be in the same directory")
+
+		cfg := viper.New()
+		cfg.Set(keyOperationStorePath, "data")
+		cfg.Set(keyOperationQueuePath, "data")
+
+		_, err = getNamespaceConfigs(cfg, newTestSidetreeConfig())
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "must not be in the same directory")
+	})
+
+	t.Run("same anchors", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:"}, {Namespace: "did:sidetree:test:"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "use the same channel and chaincode")
+	})
+
+	t.Run("same chaincode with different anchor prefix", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:"}, {Namespace: "did:sidetree:test:", AnchorPrefix: "test_"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "namespaces [did:sidetree:] and [did:sidetree:test:] use the same channel and chaincode")
+	})
+
+	t.Run("same chaincode on different channels", func(t *testing.T) {
+		sidetreeCfg := newTestSidetreeConfig()
+		sidetreeCfg.Namespaces = []namespaceConfig{{Namespace: "did:sidetree:"}, {Namespace: "did:sidetree:test:", Channel: "testchannel"}}
+
+		_, err := getNamespaceConfigs(config, sidetreeCfg)
+		require.Nil(t, err)
+	})
+}
+
+func newTestSidetreeConfig() *sidetreeConfig {
+	return &sidetreeConfig{
+		Channel:      "mychannel",
+		User:         "User1",
+		ChaincodeID:  defaultChaincodeID,
+		AnchorPrefix: defaultAnchorPrefix,
+	}
+}
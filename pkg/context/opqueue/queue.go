@@ -0,0 +1,163 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// keyPrefix is the prefix of the keys of queued operations (followed by the sequence number)
+const keyPrefix = "op~"
+
+// Queue is a durable queue of the operations which were accepted by the node but are not yet anchored,
+// backed by LevelDB. Operations are kept in the order in which they were accepted.
+type Queue struct {
+	lock sync.Mutex
+	db   *leveldb.DB
+	seq  uint64
+}
+
+// New opens (or creates) the operation queue at the given path
+func New(path string) (*Queue, error) {
+
+	db, err := leveldb.OpenFile(filepath.Clean(path), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open operation queue at %s", path)
+	}
+
+	seq, err := lastSeq(db)
+	if err != nil {
+		if e := db.Close(); e != nil {
+			return nil, errors.Wrapf(err, "failed to close operation queue: %s", e)
+		}
+
+		return nil, err
+	}
+
+	return &Queue{db: db, seq: seq}, nil
+}
+
+// Put appends the given operation to the queue. The operation is written to disk before Put returns.
+// Returns the key of the operation.
+func (q *Queue) Put(op []byte) (string, error) {
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	key := seqKey(q.seq + 1)
+
+	err := q.db.Put([]byte(key), op, &opt.WriteOptions{Sync: true})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to queue operation")
+	}
+
+	q.seq++
+
+	return key, nil
+}
+
+// Delete removes the operation with the given key from the queue
+func (q *Queue) Delete(key string) error {
+
+	err := q.db.Delete([]byte(key), &opt.WriteOptions{Sync: true})
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove operation [%s] from the queue", key)
+	}
+
+	return nil
+}
+
+// Remove removes the given operations (e.g. the operations of a batch which was anchored) from the queue.
+// Operations which are not queued are ignored.
+func (q *Queue) Remove(ops [][]byte) error {
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	hashes := make(map[[sha256.Size]byte]struct{}, len(ops))
+	for _, op := range ops {
+		hashes[sha256.Sum256(op)] = struct{}{}
+	}
+
+	it := q.db.NewIterator(util.BytesPrefix([]byte(keyPrefix)), nil)
+	defer it.Release()
+
+	b := new(leveldb.Batch)
+	for it.Next() {
+		if _, ok := hashes[sha256.Sum256(it.Value())]; ok {
+			b.Delete(append([]byte(nil), it.Key()...))
+		}
+	}
+
+	if err := it.Error(); err != nil {
+		return errors.Wrap(err, "failed to read queued operations")
+	}
+
+	err := q.db.Write(b, &opt.WriteOptions{Sync: true})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove operations from the queue")
+	}
+
+	return nil
+}
+
+// Operations returns the queued operations in the order in which they were accepted
+func (q *Queue) Operations() ([][]byte, error) {
+
+	it := q.db.NewIterator(util.BytesPrefix([]byte(keyPrefix)), nil)
+	defer it.Release()
+
+	var ops [][]byte
+	for it.Next() {
+		ops = append(ops, append([]byte(nil), it.Value()...))
+	}
+
+	if err := it.Error(); err != nil {
+		return nil, errors.Wrap(err, "failed to read queued operations")
+	}
+
+	return ops, nil
+}
+
+// Close closes the queue
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// lastSeq returns the sequence number of the last queued operation (zero if the queue is empty)
+func lastSeq(db *leveldb.DB) (uint64, error) {
+
+	it := db.NewIterator(util.BytesPrefix([]byte(keyPrefix)), nil)
+	defer it.Release()
+
+	if !it.Last() {
+		return 0, errors.Wrap(it.Error(), "failed to read queued operations")
+	}
+
+	seq, err := strconv.ParseUint(strings.TrimPrefix(string(it.Key()), keyPrefix), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid key [%s] in operation queue", it.Key())
+	}
+
+	return seq, nil
+}
+
+// seqKey returns a key which sorts operations by sequence number
+func seqKey(seq uint64) string {
+	return fmt.Sprintf("%s%020d", keyPrefix, seq)
+}
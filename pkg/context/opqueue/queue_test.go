@@ -0,0 +1,89 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opqueue
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewError(t *testing.T) {
+	f, err := ioutil.TempFile("", "opqueue")
+	require.Nil(t, err)
+	defer func() { require.Nil(t, os.Remove(f.Name())) }()
+
+	q, err := New(f.Name())
+	require.NotNil(t, err)
+	require.Nil(t, q)
+	require.Contains(t, err.Error(), "failed to open operation queue")
+}
+
+func TestQueue(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	q, err := New(dir)
+	require.Nil(t, err)
+
+	ops, err := q.Operations()
+	require.Nil(t, err)
+	require.Empty(t, ops)
+
+	key1, err := q.Put([]byte("op1"))
+	require.Nil(t, err)
+	_, err = q.Put([]byte("op2"))
+	require.Nil(t, err)
+	_, err = q.Put([]byte("op3"))
+	require.Nil(t, err)
+
+	require.Nil(t, q.Delete(key1))
+
+	ops, err = q.Operations()
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("op2"), []byte("op3")}, ops)
+
+	// the queue survives a restart and appends after the last operation
+	require.Nil(t, q.Close())
+
+	q, err = New(dir)
+	require.Nil(t, err)
+	defer func() { require.Nil(t, q.Close()) }()
+
+	_, err = q.Put([]byte("op4"))
+	require.Nil(t, err)
+
+	ops, err = q.Operations()
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("op2"), []byte("op3"), []byte("op4")}, ops)
+
+	// anchored operations are removed
+	require.Nil(t, q.Remove([][]byte{[]byte("op2"), []byte("op4"), []byte("other")}))
+
+	ops, err = q.Operations()
+	require.Nil(t, err)
+	require.Equal(t, [][]byte{[]byte("op3")}, ops)
+
+	require.Nil(t, q.Remove(nil))
+}
+
+func TestSeqKey(t *testing.T) {
+	// keys sort by sequence number
+	require.True(t, seqKey(9) < seqKey(10))
+	require.Equal(t, "op~00000000000000000010", seqKey(10))
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "opqueue")
+	require.Nil(t, err)
+
+	return dir, func() {
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
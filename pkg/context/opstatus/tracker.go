@@ -0,0 +1,308 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opstatus
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const defaultSize = 10000
+
+var logger = logrus.New()
+
+// Status is the processing status of an operation
+type Status string
+
+const (
+	// StatusPending is the status of operations which were accepted by the batch writer
+	StatusPending Status = "pending"
+	// StatusBatched is the status of operations whose batch is being anchored
+	StatusBatched Status = "batched"
+	// StatusAnchored is the status of operations whose batch was anchored
+	StatusAnchored Status = "anchored"
+	// StatusRejected is the status of operations which were rejected by the node or whose batch failed to be anchored
+	StatusRejected Status = "rejected"
+)
+
+// Config holds the limits of the tracker
+type Config struct {
+	// Size is the maximum number of tracked operations
+	Size int
+}
+
+// Anchor identifies the transaction which anchored an operation
+type Anchor struct {
+	Address     string
+	TxID        string
+	BlockNumber uint64
+}
+
+// OperationStatus is the status of an operation
+type OperationStatus struct {
+	Hash string
+	// HashAlgorithm is the multihash algorithm code with which the hash was computed
+	HashAlgorithm uint
+	Status        Status
+	// Anchor is set once the operation was anchored
+	Anchor *Anchor
+	// Reason is the reason why the operation was rejected
+	Reason string
+}
+
+// protocolClient provides the hash algorithm of the current protocol version
+type protocolClient interface {
+	Current() protocolApi.Protocol
+}
+
+// operationStore provides the operations which were anchored by operation hash
+type operationStore interface {
+	GetByHash(hash string) (*batch.Operation, bool, error)
+}
+
+// operationRequest defines the part of an operation request which identifies the operation
+type operationRequest struct {
+	Payload string `json:"payload"`
+}
+
+// entry is a tracked operation. The operation is identified by the fingerprint of its encoded payload
+// so that its hash is computed once (with the hash algorithm of the protocol version which was current
+// when the operation was first tracked) and does not change if the protocol version changes.
+type entry struct {
+	fingerprint [sha256.Size]byte
+	status      OperationStatus
+}
+
+// operationKey identifies an operation which is about to be tracked. The hash is not set (and hashErr
+// is set) if it cannot be computed, in which case only an operation which is already tracked is updated.
+type operationKey struct {
+	fingerprint   [sha256.Size]byte
+	hash          string
+	hashAlgorithm uint
+	hashErr       error
+}
+
+// Tracker tracks the status of the operations of a namespace by operation hash. The hash of an operation
+// is the multihash of its encoded payload in base64url encoding. Only the most recently updated operations
+// are tracked and the status is lost when the node restarts (operations which are replayed from the
+// operation queue are pending again). Operations which are not tracked as anchored are looked up in the
+// operation store.
+type Tracker struct {
+	protocolClient protocolClient
+	store          operationStore
+
+	lock         sync.Mutex
+	maxSize      int
+	entries      *list.List
+	hashes       map[string]*list.Element
+	fingerprints map[[sha256.Size]byte]*list.Element
+}
+
+// New returns a new tracker
+func New(pc protocolClient, store operationStore, cfg Config) *Tracker {
+
+	maxSize := cfg.Size
+	if maxSize <= 0 {
+		maxSize = defaultSize
+	}
+
+	return &Tracker{
+		protocolClient: pc,
+		store:          store,
+		maxSize:        maxSize,
+		entries:        list.New(),
+		hashes:         make(map[string]*list.Element),
+		fingerprints:   make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Get returns the status of the operation with the given hash. If the operation is not tracked as anchored
+// (e.g. it was evicted, the node was restarted or the outcome of its anchor was unknown) but is found in the
+// operation store then it is reported as anchored. False is returned if the operation is not known.
+func (t *Tracker) Get(hash string) (*OperationStatus, bool, error) {
+
+	status, ok := t.get(hash)
+	if ok && status.Status == StatusAnchored {
+		return status, true, nil
+	}
+
+	op, found, err := t.store.GetByHash(hash)
+	if err != nil {
+		return nil, false, errors.WithMessage(err, "failed to read operation from the operation store")
+	}
+
+	if !found {
+		return status, ok, nil
+	}
+
+	return &OperationStatus{
+		Hash:          hash,
+		HashAlgorithm: op.HashAlgorithmInMultiHashCode,
+		Status:        StatusAnchored,
+		Anchor:        &Anchor{BlockNumber: op.TransactionTime},
+	}, true, nil
+}
+
+func (t *Tracker) get(hash string) (*OperationStatus, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	elem, ok := t.hashes[hash]
+	if !ok {
+		return nil, false
+	}
+
+	status := elem.Value.(*entry).status
+
+	return &status, true
+}
+
+// Accepted records that the given operation was accepted by the batch writer
+func (t *Tracker) Accepted(op []byte) {
+	t.update([][]byte{op}, OperationStatus{Status: StatusPending})
+}
+
+// Pending records that the given operations are pending again, i.e. their batch failed to be anchored
+// but was not rejected (the operations remain queued and the batch may still be anchored)
+func (t *Tracker) Pending(ops [][]byte) {
+	t.update(ops, OperationStatus{Status: StatusPending})
+}
+
+// Batched records that the batch of the given operations is being anchored
+func (t *Tracker) Batched(ops [][]byte) {
+	t.update(ops, OperationStatus{Status: StatusBatched})
+}
+
+// Anchored records that the batch of the given operations was anchored
+func (t *Tracker) Anchored(ops [][]byte, anchor Anchor) {
+	t.update(ops, OperationStatus{Status: StatusAnchored, Anchor: &anchor})
+}
+
+// Rejected records that the given operations were rejected or failed to be anchored
+func (t *Tracker) Rejected(ops [][]byte, reason string) {
+	t.update(ops, OperationStatus{Status: StatusRejected, Reason: reason})
+}
+
+// RejectedRequest records that the operation request with the given encoded payload was rejected. The status
+// of an operation which is already tracked (e.g. which was submitted before) is not changed.
+func (t *Tracker) RejectedRequest(encodedPayload string, reason string) {
+
+	key, err := t.key(encodedPayload)
+	if err != nil {
+		logger.Warnf("Failed to track rejected operation: %s", err)
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.fingerprints[key.fingerprint]; ok {
+		return
+	}
+
+	t.put(key, OperationStatus{Status: StatusRejected, Reason: reason})
+}
+
+// update sets the status of the given operations. Operations which cannot be hashed are not tracked.
+func (t *Tracker) update(ops [][]byte, status OperationStatus) {
+
+	keys := make([]*operationKey, 0, len(ops))
+	for _, op := range ops {
+		key, err := t.operationKey(op)
+		if err != nil {
+			logger.Warnf("Failed to track the status of an operation: %s", err)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, key := range keys {
+		t.put(key, status)
+	}
+}
+
+// put sets the status of an operation and evicts the least recently updated operations until the
+// tracker is within its size. An operation which is already tracked keeps its hash.
+func (t *Tracker) put(key *operationKey, status OperationStatus) {
+
+	if elem, ok := t.fingerprints[key.fingerprint]; ok {
+		e := elem.Value.(*entry)
+		status.Hash = e.status.Hash
+		status.HashAlgorithm = e.status.HashAlgorithm
+		e.status = status
+		t.entries.MoveToFront(elem)
+		return
+	}
+
+	if key.hashErr != nil {
+		logger.Warnf("Failed to track the status of an operation: %s", key.hashErr)
+		return
+	}
+
+	status.Hash = key.hash
+	status.HashAlgorithm = key.hashAlgorithm
+
+	elem := t.entries.PushFront(&entry{fingerprint: key.fingerprint, status: status})
+	t.fingerprints[key.fingerprint] = elem
+	t.hashes[key.hash] = elem
+
+	for t.entries.Len() > t.maxSize {
+		oldest := t.entries.Back()
+		t.entries.Remove(oldest)
+
+		e := oldest.Value.(*entry)
+		delete(t.fingerprints, e.fingerprint)
+		delete(t.hashes, e.status.Hash)
+	}
+}
+
+// operationKey returns the key of the given operation request
+func (t *Tracker) operationKey(op []byte) (*operationKey, error) {
+
+	req := &operationRequest{}
+	if err := json.Unmarshal(op, req); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal operation")
+	}
+
+	return t.key(req.Payload)
+}
+
+// key returns the key of the operation with the given encoded payload. The hash is computed with the
+// hash algorithm of the current protocol version.
+func (t *Tracker) key(encodedPayload string) (*operationKey, error) {
+
+	if encodedPayload == "" {
+		return nil, errors.New("operation has no payload")
+	}
+
+	key := &operationKey{
+		fingerprint:   sha256.Sum256([]byte(encodedPayload)),
+		hashAlgorithm: t.protocolClient.Current().HashAlgorithmInMultiHashCode,
+	}
+
+	multihash, err := docutil.ComputeMultihash(key.hashAlgorithm, []byte(encodedPayload))
+	if err != nil {
+		key.hashErr = errors.Wrapf(err, "hash algorithm [%d] not supported", key.hashAlgorithm)
+		return key, nil
+	}
+
+	key.hash = docutil.EncodeToString(multihash)
+
+	return key, nil
+}
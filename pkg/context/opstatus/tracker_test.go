@@ -0,0 +1,228 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package opstatus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const sha256Code = 18
+
+func TestTracker(t *testing.T) {
+	tracker := New(newMockProtocolClient(), newMockStore(), Config{})
+	require.Equal(t, defaultSize, tracker.maxSize)
+
+	op1, op2 := operation("payload1"), operation("payload2")
+	hash1, hash2 := payloadHash(t, "payload1"), payloadHash(t, "payload2")
+
+	_, ok := get(t, tracker, hash1)
+	require.False(t, ok)
+
+	tracker.Accepted(op1)
+	tracker.Accepted(op2)
+
+	status, ok := get(t, tracker, hash1)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash1, HashAlgorithm: sha256Code, Status: StatusPending}, status)
+
+	tracker.Batched([][]byte{op1, op2})
+
+	status, ok = get(t, tracker, hash2)
+	require.True(t, ok)
+	require.Equal(t, StatusBatched, status.Status)
+
+	// the batch failed to be anchored but was not rejected
+	tracker.Pending([][]byte{op1, op2})
+
+	status, ok = get(t, tracker, hash2)
+	require.True(t, ok)
+	require.Equal(t, StatusPending, status.Status)
+
+	anchor := Anchor{Address: "address", TxID: "txID", BlockNumber: 5}
+	tracker.Anchored([][]byte{op1, op2}, anchor)
+
+	status, ok = get(t, tracker, hash1)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash1, HashAlgorithm: sha256Code, Status: StatusAnchored, Anchor: &anchor}, status)
+
+	// the returned status is a copy
+	status.Status = StatusRejected
+
+	status, ok = get(t, tracker, hash1)
+	require.True(t, ok)
+	require.Equal(t, StatusAnchored, status.Status)
+
+	tracker.Rejected([][]byte{op2}, "failed to anchor batch")
+
+	status, ok = get(t, tracker, hash2)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash2, HashAlgorithm: sha256Code, Status: StatusRejected, Reason: "failed to anchor batch"}, status)
+}
+
+func TestTracker_RejectedRequest(t *testing.T) {
+	tracker := New(newMockProtocolClient(), newMockStore(), Config{})
+
+	tracker.RejectedRequest("payload1", "invalid operation")
+
+	hash := payloadHash(t, "payload1")
+	status, ok := get(t, tracker, hash)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash, HashAlgorithm: sha256Code, Status: StatusRejected, Reason: "invalid operation"}, status)
+
+	// a request which is rejected after its operation was accepted does not change the status
+	tracker.Accepted(operation("payload2"))
+	tracker.RejectedRequest("payload2", "duplicate operation")
+
+	status, ok = get(t, tracker, payloadHash(t, "payload2"))
+	require.True(t, ok)
+	require.Equal(t, StatusPending, status.Status)
+
+	// requests without payload are not tracked
+	tracker.RejectedRequest("", "missing payload")
+	require.Len(t, tracker.hashes, 2)
+}
+
+func TestTracker_Evict(t *testing.T) {
+	tracker := New(newMockProtocolClient(), newMockStore(), Config{Size: 2})
+
+	tracker.Accepted(operation("payload1"))
+	tracker.Accepted(operation("payload2"))
+
+	// updating an operation makes it the most recent one
+	tracker.Batched([][]byte{operation("payload1")})
+	tracker.Accepted(operation("payload3"))
+
+	_, ok := get(t, tracker, payloadHash(t, "payload2"))
+	require.False(t, ok)
+
+	for _, payload := range []string{"payload1", "payload3"} {
+		_, ok = get(t, tracker, payloadHash(t, payload))
+		require.True(t, ok, payload)
+	}
+}
+
+func TestTracker_InvalidOperation(t *testing.T) {
+	pc := newMockProtocolClient()
+	tracker := New(pc, newMockStore(), Config{})
+
+	// invalid operations are not tracked
+	tracker.Accepted([]byte("{"))
+	tracker.Accepted([]byte("{}"))
+	require.Empty(t, tracker.hashes)
+
+	pc.protocol.HashAlgorithmInMultiHashCode = 55
+	tracker.Accepted(operation("payload1"))
+	require.Empty(t, tracker.hashes)
+
+	key, err := tracker.key("payload1")
+	require.Nil(t, err)
+	require.NotNil(t, key.hashErr)
+	require.Contains(t, key.hashErr.Error(), "hash algorithm [55] not supported")
+}
+
+func TestTracker_ProtocolChange(t *testing.T) {
+	pc := newMockProtocolClient()
+	tracker := New(pc, newMockStore(), Config{})
+
+	tracker.Accepted(operation("payload1"))
+
+	// the operation keeps the hash which was computed with the hash algorithm in force when it was accepted
+	pc.protocol.HashAlgorithmInMultiHashCode = 55
+	tracker.Batched([][]byte{operation("payload1")})
+
+	hash := payloadHash(t, "payload1")
+	status, ok := get(t, tracker, hash)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash, HashAlgorithm: sha256Code, Status: StatusBatched}, status)
+	require.Len(t, tracker.hashes, 1)
+}
+
+func TestTracker_OperationStore(t *testing.T) {
+	store := newMockStore()
+	tracker := New(newMockProtocolClient(), store, Config{})
+
+	hash1, hash2 := payloadHash(t, "payload1"), payloadHash(t, "payload2")
+	store.ops[hash1] = &batch.Operation{EncodedPayload: "payload1", HashAlgorithmInMultiHashCode: sha256Code, TransactionTime: 7}
+	store.ops[hash2] = &batch.Operation{EncodedPayload: "payload2", HashAlgorithmInMultiHashCode: sha256Code, TransactionTime: 8}
+
+	// operations which are not tracked are looked up in the operation store
+	status, ok := get(t, tracker, hash1)
+	require.True(t, ok)
+	require.Equal(t, &OperationStatus{Hash: hash1, HashAlgorithm: sha256Code, Status: StatusAnchored, Anchor: &Anchor{BlockNumber: 7}}, status)
+
+	// as are operations whose anchor failed with an unknown outcome
+	tracker.Pending([][]byte{operation("payload2")})
+
+	status, ok = get(t, tracker, hash2)
+	require.True(t, ok)
+	require.Equal(t, StatusAnchored, status.Status)
+	require.Equal(t, uint64(8), status.Anchor.BlockNumber)
+
+	_, ok = get(t, tracker, payloadHash(t, "payload3"))
+	require.False(t, ok)
+
+	store.err = errors.New("store error")
+	_, _, err := tracker.Get(hash1)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "store error")
+}
+
+func operation(payload string) []byte {
+	return []byte(fmt.Sprintf(`{"header":{"operation":"create"},"payload":"%s","signature":"signature"}`, payload))
+}
+
+func payloadHash(t *testing.T, payload string) string {
+	hash, err := docutil.ComputeMultihash(sha256Code, []byte(payload))
+	require.Nil(t, err)
+
+	return docutil.EncodeToString(hash)
+}
+
+func get(t *testing.T, tracker *Tracker, hash string) (*OperationStatus, bool) {
+	status, ok, err := tracker.Get(hash)
+	require.Nil(t, err)
+
+	return status, ok
+}
+
+type mockStore struct {
+	ops map[string]*batch.Operation
+	err error
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{ops: make(map[string]*batch.Operation)}
+}
+
+func (m *mockStore) GetByHash(hash string) (*batch.Operation, bool, error) {
+	if m.err != nil {
+		return nil, false, m.err
+	}
+
+	op, ok := m.ops[hash]
+
+	return op, ok, nil
+}
+
+type mockProtocolClient struct {
+	protocol protocol.Protocol
+}
+
+func newMockProtocolClient() *mockProtocolClient {
+	return &mockProtocolClient{protocol: protocol.Protocol{HashAlgorithmInMultiHashCode: sha256Code}}
+}
+
+func (m *mockProtocolClient) Current() protocol.Protocol {
+	return m.protocol
+}
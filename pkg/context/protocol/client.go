@@ -11,34 +11,212 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"sort"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
 )
 
+// blockHeightTTL is the time for which the block height of the channel is cached
+const blockHeightTTL = 5 * time.Second
+
+var logger = logrus.New()
+
+// blockHeightProvider returns the height of the channel (i.e. the number of the last block plus one)
+type blockHeightProvider interface {
+	BlockHeight() (uint64, error)
+}
+
 // Client is a struct which holds a list of protocols.
 type Client struct {
-	protocols []protocol.Protocol
+	lock      sync.RWMutex
+	protocols []protocolVersion
+	load      func() ([]protocolVersion, error)
+
+	heightLock     sync.Mutex
+	heightProvider blockHeightProvider
+	height         uint64
+	heightTime     time.Time
+	heightTTL      time.Duration
 }
 
-//New initializes the protocol parameters from file
-func New(protocolFileName string) (*Client, error) {
+// protocolVersion holds the protocol parameters of a version
+type protocolVersion struct {
+	version string
+	protocol.Protocol
+}
+
+//New initializes the protocol parameters from file. The current protocol version is determined
+// by the block height of the channel which is provided by the given provider.
+func New(protocolFileName string, bhp blockHeightProvider) (*Client, error) {
 
 	protocolFileName = filepath.Clean(protocolFileName)
+
+	return newClient(func() ([]protocolVersion, error) {
+		return loadFile(protocolFileName)
+	}, bhp)
+}
+
+func newClient(load func() ([]protocolVersion, error), bhp blockHeightProvider) (*Client, error) {
+
+	c := &Client{load: load, heightProvider: bhp, heightTTL: blockHeightTTL}
+
+	err := c.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+//Current returns the protocol version in force at the current block height of the channel, i.e. the version
+// which applies to the next block. A version which starts at a later block is not used before that block.
+func (c *Client) Current() protocol.Protocol {
+	return c.current().Protocol
+}
+
+// CurrentVersion returns the name of the protocol version in force at the current block height of the channel
+func (c *Client) CurrentVersion() string {
+	return c.current().version
+}
+
+// Get returns the protocol version in force at the given blockchain time (block number)
+func (c *Client) Get(blockchainTime uint64) (protocol.Protocol, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	p, ok := c.versionAt(blockchainTime)
+	if !ok {
+		return protocol.Protocol{}, errors.Errorf("protocol parameters are not defined for blockchain time %d", blockchainTime)
+	}
+
+	return p.Protocol, nil
+}
+
+func (c *Client) current() protocolVersion {
+
+	height := c.blockHeight()
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	p, ok := c.versionAt(height)
+	if !ok {
+		// no version has started yet so the earliest version applies
+		return c.protocols[0]
+	}
+
+	return p
+}
+
+// versionAt returns the protocol version in force at the given blockchain time
+func (c *Client) versionAt(blockchainTime uint64) (protocolVersion, bool) {
+
+	for i := len(c.protocols) - 1; i >= 0; i-- {
+		if c.protocols[i].StartingBlockChainTime <= blockchainTime {
+			return c.protocols[i], true
+		}
+	}
+
+	return protocolVersion{}, false
+}
+
+// blockHeight returns the block height of the channel, which is cached for heightTTL. The last known
+// height is returned if the height cannot be determined. Failures are cached as well so that the
+// height is not queried on every call while the peers are unavailable.
+func (c *Client) blockHeight() uint64 {
+
+	c.heightLock.Lock()
+	defer c.heightLock.Unlock()
+
+	if !c.heightTime.IsZero() && time.Since(c.heightTime) < c.heightTTL {
+		return c.height
+	}
+
+	c.heightTime = time.Now()
+
+	height, err := c.heightProvider.BlockHeight()
+	if err != nil {
+		logger.Warnf("Failed to determine the block height - using the last known height %d: %s", c.height, err)
+		return c.height
+	}
+
+	c.height = height
+
+	return height
+}
+
+// Refresh reloads the protocol versions from their source. The current protocol
+// versions are kept if the protocol versions cannot be loaded.
+func (c *Client) Refresh() error {
+
+	protocols, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.protocols = protocols
+
+	return nil
+}
+
+func loadFile(protocolFileName string) ([]protocolVersion, error) {
+
 	protocolParameterFileBytes, err := ioutil.ReadFile(protocolFileName) //nolint:gas
 	if err != nil {
 		return nil, err
 	}
 
+	if len(protocolParameterFileBytes) == 0 {
+		return nil, errors.Errorf("protocol file [%s] is empty", protocolFileName)
+	}
+
+	protocols, err := parseProtocols(protocolParameterFileBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid protocol file [%s]", protocolFileName)
+	}
+
+	return protocols, nil
+}
+
+// parseProtocols parses the given protocol versions (JSON) and returns them sorted by blockchain start time
+func parseProtocols(protocolVersionsBytes []byte) ([]protocolVersion, error) {
+
 	var protocolVersions map[string]protocol.Protocol
-	err = json.Unmarshal(protocolParameterFileBytes, &protocolVersions)
+	err := json.Unmarshal(protocolVersionsBytes, &protocolVersions)
 	if err != nil {
 		return nil, err
 	}
 
+	return sortProtocols(protocolVersions)
+}
+
+// sortProtocols validates the given protocol versions and returns them sorted by blockchain start time
+func sortProtocols(protocolVersions map[string]protocol.Protocol) ([]protocolVersion, error) {
+
+	if len(protocolVersions) == 0 {
+		return nil, errors.New("no protocol versions defined")
+	}
+
 	// Creating the list of the protocol versions
-	protocols := make([]protocol.Protocol, 0, len(protocolVersions))
-	for _, v := range protocolVersions {
-		protocols = append(protocols, v)
+	protocols := make([]protocolVersion, 0, len(protocolVersions))
+	startTimes := make(map[uint64]string)
+	for version, p := range protocolVersions {
+		if err := validate(p); err != nil {
+			return nil, errors.Wrapf(err, "protocol version [%s]", version)
+		}
+
+		if other, ok := startTimes[p.StartingBlockChainTime]; ok {
+			return nil, errors.Errorf("protocol versions [%s] and [%s] have the same starting blockchain time", other, version)
+		}
+
+		startTimes[p.StartingBlockChainTime] = version
+		protocols = append(protocols, protocolVersion{version: version, Protocol: p})
 	}
 
 	// Sorting the protocolParameter list based on blockChain start time
@@ -46,10 +224,22 @@ func New(protocolFileName string) (*Client, error) {
 		return protocols[j].StartingBlockChainTime > protocols[i].StartingBlockChainTime
 	})
 
-	return &Client{protocols: protocols}, nil
+	return protocols, nil
 }
 
-//Current returns the latest version of protocol
-func (c *Client) Current() protocol.Protocol {
-	return c.protocols[len(c.protocols)-1]
+func validate(p protocol.Protocol) error {
+
+	if p.HashAlgorithmInMultiHashCode == 0 {
+		return errors.New("hashAlgorithmInMultihashCode is required")
+	}
+
+	if p.MaxOperationsPerBatch == 0 {
+		return errors.New("maxOperationsPerBatch is required")
+	}
+
+	if p.MaxOperationByteSize == 0 {
+		return errors.New("maxOperationByteSize is required")
+	}
+
+	return nil
 }
@@ -7,29 +7,193 @@ SPDX-License-Identifier: Apache-2.0
 package protocol
 
 import (
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
 )
 
 func TestNew(t *testing.T) {
-	client, err := New("testdata/protocol.json")
+	client, err := New("testdata/protocol.json", &mockBlockHeight{})
 	require.Nil(t, err)
 	require.NotNil(t, client)
 }
 
 func TestNewError(t *testing.T) {
-	client, err := New("testdata/invalid.json")
+	client, err := New("testdata/invalid.json", &mockBlockHeight{})
 	require.NotNil(t, err)
 	require.Nil(t, client)
 	require.Contains(t, err.Error(), "no such file or directory")
 }
 
 func TestCurrentProtocol(t *testing.T) {
-	client, err := New("testdata/protocol.json")
+	bh := &mockBlockHeight{height: 499999}
+
+	client, err := New("testdata/protocol.json", bh)
 	require.Nil(t, err)
 	require.NotNil(t, client)
 
+	client.heightTTL = 0
+
+	// version 1.0 starts at block 500000 so it is not in force yet
 	protocol := client.Current()
+	require.Equal(t, uint(100), protocol.MaxOperationsPerBatch)
+	require.Equal(t, "0.1", client.CurrentVersion())
+
+	bh.set(500000, nil)
+
+	protocol = client.Current()
+	require.Equal(t, uint(10000), protocol.MaxOperationsPerBatch)
+	require.Equal(t, "1.0", client.CurrentVersion())
+
+	// the last known height is used if the height cannot be determined
+	bh.set(0, errors.New("query error"))
+	require.Equal(t, "1.0", client.CurrentVersion())
+}
+
+func TestCurrentProtocolNotStarted(t *testing.T) {
+	client := &Client{
+		protocols: []protocolVersion{
+			{version: "1.0", Protocol: protocolApi.Protocol{StartingBlockChainTime: 100}},
+			{version: "2.0", Protocol: protocolApi.Protocol{StartingBlockChainTime: 200}},
+		},
+		heightProvider: &mockBlockHeight{height: 10},
+	}
+
+	// the earliest version applies until a version has started
+	require.Equal(t, "1.0", client.CurrentVersion())
+}
+
+func TestCurrentProtocolHeightCache(t *testing.T) {
+	bh := &mockBlockHeight{height: 10}
+
+	client, err := New("testdata/protocol.json", bh)
+	require.Nil(t, err)
+
+	require.Equal(t, "0.1", client.CurrentVersion())
+
+	// the cached height is used
+	bh.set(500000, nil)
+	require.Equal(t, "0.1", client.CurrentVersion())
+	require.Equal(t, 1, bh.getQueries())
+}
+
+func TestCurrentProtocolHeightCacheError(t *testing.T) {
+	bh := &mockBlockHeight{height: 500000}
+
+	client, err := New("testdata/protocol.json", bh)
+	require.Nil(t, err)
+
+	client.heightTTL = 0
+	require.Equal(t, "1.0", client.CurrentVersion())
+
+	// the failure is cached with the last known height, so the height is not queried again
+	client.heightTTL = time.Minute
+	client.heightTime = time.Time{}
+	bh.set(0, errors.New("query error"))
+
+	require.Equal(t, "1.0", client.CurrentVersion())
+	require.Equal(t, "1.0", client.CurrentVersion())
+	require.Equal(t, 2, bh.getQueries())
+}
+
+func TestNewEmptyFile(t *testing.T) {
+	client, err := New("testdata/empty.json", &mockBlockHeight{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "is empty")
+
+	client, err = New("testdata/noversions.json", &mockBlockHeight{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "no protocol versions defined")
+}
+
+func TestNewInvalidFile(t *testing.T) {
+	client, err := New("testdata/invalid-json.json", &mockBlockHeight{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "invalid protocol file")
+
+	client, err = New("testdata/invalid-version.json", &mockBlockHeight{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "protocol version [0.1]: maxOperationsPerBatch is required")
+
+	client, err = New("testdata/duplicate-start.json", &mockBlockHeight{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "have the same starting blockchain time")
+}
+
+func TestGetProtocol(t *testing.T) {
+	client, err := New("testdata/protocol.json", &mockBlockHeight{})
+	require.Nil(t, err)
+	require.NotNil(t, client)
+
+	protocol, err := client.Get(0)
+	require.Nil(t, err)
+	require.Equal(t, uint(100), protocol.MaxOperationsPerBatch)
+
+	protocol, err = client.Get(499999)
+	require.Nil(t, err)
+	require.Equal(t, uint(100), protocol.MaxOperationsPerBatch)
+
+	protocol, err = client.Get(500000)
+	require.Nil(t, err)
 	require.Equal(t, uint(10000), protocol.MaxOperationsPerBatch)
+
+	protocol, err = client.Get(1000000)
+	require.Nil(t, err)
+	require.Equal(t, uint(10000), protocol.MaxOperationsPerBatch)
+}
+
+func TestGetProtocolNotDefined(t *testing.T) {
+	client := &Client{protocols: []protocolVersion{{version: "1.0", Protocol: protocolApi.Protocol{StartingBlockChainTime: 100}}}}
+
+	_, err := client.Get(99)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "not defined for blockchain time 99")
+}
+
+func TestRefresh(t *testing.T) {
+	client, err := New("testdata/protocol.json", &mockBlockHeight{})
+	require.Nil(t, err)
+
+	require.Nil(t, client.Refresh())
+	require.Equal(t, uint(100), client.Current().MaxOperationsPerBatch)
+}
+
+type mockBlockHeight struct {
+	mutex   sync.Mutex
+	height  uint64
+	err     error
+	queries int
+}
+
+func (m *mockBlockHeight) BlockHeight() (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.queries++
+
+	return m.height, m.err
+}
+
+func (m *mockBlockHeight) set(height uint64, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.height = height
+	m.err = err
+}
+
+func (m *mockBlockHeight) getQueries() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.queries
 }
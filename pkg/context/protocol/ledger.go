@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+)
+
+const (
+	getProtocolFcn = "getProtocol"
+)
+
+type chClient interface {
+	Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error)
+}
+
+// ledgerProvider provides the block height of the channel and the blocks of transactions
+type ledgerProvider interface {
+	blockHeightProvider
+	TransactionBlock(txID string) (uint64, error)
+}
+
+// ledgerLoader loads the protocol version table from the state of the Sidetree transaction chaincode
+type ledgerLoader struct {
+	lock            sync.RWMutex
+	channelProvider context.ChannelProvider
+	channelClient   chClient
+	ccID            string
+	ledger          ledgerProvider
+	txBlocks        map[string]uint64
+}
+
+// NewFromLedger initializes the protocol parameters from the protocol version table which is stored
+// on the ledger by the given Sidetree transaction chaincode. The current protocol version is determined
+// by the block height of the channel which is provided by the given provider.
+func NewFromLedger(channelProvider context.ChannelProvider, ccID string, lp ledgerProvider) (*Client, error) {
+
+	l := &ledgerLoader{channelProvider: channelProvider, ccID: ccID, ledger: lp, txBlocks: make(map[string]uint64)}
+
+	return newClient(l.load, lp)
+}
+
+func (l *ledgerLoader) load() ([]protocolVersion, error) {
+
+	client, err := l.getClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get channel client")
+	}
+
+	response, err := client.Query(channel.Request{
+		ChaincodeID: l.ccID,
+		Fcn:         getProtocolFcn,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query protocol versions")
+	}
+
+	protocols, err := parseProtocols(response.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid protocol versions on the ledger")
+	}
+
+	return l.checkStart(protocols, response.Payload)
+}
+
+// checkStart removes the protocol versions which do not start after the block of the transaction which added
+// them (since they would change the rules for blocks which may already have been processed). The chaincode
+// records the transaction of each version except for the first version.
+func (l *ledgerLoader) checkStart(protocols []protocolVersion, protocolVersionsBytes []byte) ([]protocolVersion, error) {
+
+	var txIDs map[string]struct {
+		TxID string `json:"txID"`
+	}
+	if err := json.Unmarshal(protocolVersionsBytes, &txIDs); err != nil {
+		return nil, errors.Wrap(err, "invalid protocol versions on the ledger")
+	}
+
+	valid := make([]protocolVersion, 0, len(protocols))
+	for _, p := range protocols {
+		txID := txIDs[p.version].TxID
+		if txID == "" {
+			valid = append(valid, p)
+			continue
+		}
+
+		blockNum, err := l.txBlock(txID)
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("failed to determine the block of protocol version [%s]", p.version))
+		}
+
+		if p.StartingBlockChainTime <= blockNum {
+			logger.Errorf("Ignoring protocol version [%s] since it starts at block %d which is not after block %d in which it was added",
+				p.version, p.StartingBlockChainTime, blockNum)
+			continue
+		}
+
+		valid = append(valid, p)
+	}
+
+	if len(valid) == 0 {
+		return nil, errors.New("invalid protocol versions on the ledger: no valid protocol versions defined")
+	}
+
+	return valid, nil
+}
+
+// txBlock returns the number of the block which contains the given transaction
+func (l *ledgerLoader) txBlock(txID string) (uint64, error) {
+
+	l.lock.RLock()
+	blockNum, ok := l.txBlocks[txID]
+	l.lock.RUnlock()
+
+	if ok {
+		return blockNum, nil
+	}
+
+	blockNum, err := l.ledger.TransactionBlock(txID)
+	if err != nil {
+		return 0, err
+	}
+
+	l.lock.Lock()
+	l.txBlocks[txID] = blockNum
+	l.lock.Unlock()
+
+	return blockNum, nil
+}
+
+func (l *ledgerLoader) getClient() (chClient, error) {
+
+	l.lock.RLock()
+	chc := l.channelClient
+	l.lock.RUnlock()
+
+	if chc != nil {
+		return chc, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.channelClient == nil {
+		channelClient, err := channel.New(l.channelProvider)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create channel client")
+		}
+
+		l.channelClient = channelClient
+	}
+
+	return l.channelClient, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+)
+
+const (
+	ccID = "sidetreetxn_cc"
+
+	protocolVersions = `{
+		"0.1": {"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100}
+	}`
+	updatedProtocolVersions = `{
+		"0.1": {"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100},
+		"1.0": {"startingBlockchainTime":1000,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":2000,"maxOperationsPerBatch":10000}
+	}`
+)
+
+func TestNewFromLedger(t *testing.T) {
+	cc := &mockChannelClient{payload: []byte(protocolVersions)}
+
+	client, err := newLedgerClient(cc)
+	require.Nil(t, err)
+	require.NotNil(t, client)
+	require.Equal(t, ccID, cc.request.ChaincodeID)
+	require.Equal(t, getProtocolFcn, cc.request.Fcn)
+
+	require.Equal(t, uint(100), client.Current().MaxOperationsPerBatch)
+
+	// a new protocol version was added on the ledger
+	cc.payload = []byte(updatedProtocolVersions)
+	require.Nil(t, client.Refresh())
+
+	require.Equal(t, uint(10000), client.Current().MaxOperationsPerBatch)
+	require.Equal(t, "1.0", client.CurrentVersion())
+
+	protocol, err := client.Get(999)
+	require.Nil(t, err)
+	require.Equal(t, uint(100), protocol.MaxOperationsPerBatch)
+
+	// the current protocol versions are kept if the refresh fails
+	cc.err = errors.New("query error")
+	err = client.Refresh()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "query error")
+	require.Equal(t, uint(10000), client.Current().MaxOperationsPerBatch)
+}
+
+func TestNewFromLedgerError(t *testing.T) {
+	client, err := newLedgerClient(&mockChannelClient{err: errors.New("query error")})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "failed to query protocol versions")
+
+	client, err = newLedgerClient(&mockChannelClient{payload: []byte("{}")})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), "invalid protocol versions on the ledger: no protocol versions defined")
+}
+
+func TestNewFromLedgerProviderError(t *testing.T) {
+	testErr := errors.New("provider error")
+
+	client, err := NewFromLedger(func() (context.Channel, error) { return nil, testErr }, ccID, &mockLedger{})
+	require.NotNil(t, err)
+	require.Nil(t, client)
+	require.Contains(t, err.Error(), testErr.Error())
+}
+
+func TestNewFromLedgerStartCheck(t *testing.T) {
+	versions := `{
+		"0.1": {"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100},
+		"1.0": {"startingBlockchainTime":1000,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":2000,"maxOperationsPerBatch":10000,"txID":"tx1"}
+	}`
+
+	cc := &mockChannelClient{payload: []byte(versions)}
+	lp := &mockLedger{txBlocks: map[string]uint64{"tx1": 999}}
+
+	client, err := newLedgerClientWithLedger(cc, lp)
+	require.Nil(t, err)
+	require.Equal(t, "1.0", client.CurrentVersion())
+
+	// the block of the transaction is cached
+	require.Nil(t, client.Refresh())
+	require.Equal(t, 1, lp.queries)
+
+	// a version which does not start after the block in which it was added is ignored
+	lp.txBlocks["tx2"] = 1000
+	cc.payload = []byte(`{
+		"0.1": {"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100},
+		"1.0": {"startingBlockchainTime":1000,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":2000,"maxOperationsPerBatch":10000,"txID":"tx2"}
+	}`)
+
+	client, err = newLedgerClientWithLedger(cc, lp)
+	require.Nil(t, err)
+	require.Equal(t, "0.1", client.CurrentVersion())
+
+	protocol, err := client.Get(1000)
+	require.Nil(t, err)
+	require.Equal(t, uint(100), protocol.MaxOperationsPerBatch)
+
+	// the protocol versions cannot be loaded if the block of a transaction cannot be determined
+	cc.payload = []byte(`{
+		"0.1": {"startingBlockchainTime":0,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":500,"maxOperationsPerBatch":100},
+		"1.0": {"startingBlockchainTime":1000,"hashAlgorithmInMultihashCode":18,"maxOperationByteSize":2000,"maxOperationsPerBatch":10000,"txID":"tx3"}
+	}`)
+
+	err = client.Refresh()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to determine the block of protocol version [1.0]")
+	require.Equal(t, "0.1", client.CurrentVersion())
+}
+
+func newLedgerClient(cc chClient) (*Client, error) {
+	return newLedgerClientWithLedger(cc, &mockLedger{})
+}
+
+func newLedgerClientWithLedger(cc chClient, lp *mockLedger) (*Client, error) {
+	lp.height = 1000
+
+	l := &ledgerLoader{channelClient: cc, ccID: ccID, ledger: lp, txBlocks: make(map[string]uint64)}
+	return newClient(l.load, lp)
+}
+
+type mockLedger struct {
+	mockBlockHeight
+	txBlocks map[string]uint64
+	queries  int
+}
+
+func (m *mockLedger) TransactionBlock(txID string) (uint64, error) {
+	m.queries++
+
+	blockNum, ok := m.txBlocks[txID]
+	if !ok {
+		return 0, errors.Errorf("transaction [%s] not found", txID)
+	}
+
+	return blockNum, nil
+}
+
+type mockChannelClient struct {
+	request channel.Request
+	payload []byte
+	err     error
+}
+
+func (m *mockChannelClient) Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	m.request = request
+
+	if m.err != nil {
+		return channel.Response{}, m.err
+	}
+
+	return channel.Response{Payload: m.payload}, nil
+}
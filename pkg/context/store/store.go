@@ -0,0 +1,213 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const (
+	// operationKeyPrefix is the prefix of all operation keys
+	operationKeyPrefix = "op~"
+	// keySeparator separates the unique suffix from the ledger position in an operation key
+	keySeparator = "~"
+	// hashKeyPrefix is the prefix of the keys which map operation hashes to operation keys
+	hashKeyPrefix = "hash~"
+	// lastBlockKey holds the number of the last block whose operations were added to the store
+	lastBlockKey = "lastblock"
+)
+
+// Store implements a persistent operation store backed by LevelDB. Operations are
+// keyed by unique suffix and ledger position so that they are returned in ledger order.
+type Store struct {
+	db *leveldb.DB
+}
+
+// New opens (or creates) the operation store at the given path
+func New(path string) (*Store, error) {
+
+	db, err := leveldb.OpenFile(filepath.Clean(path), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open operation store at %s", path)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Put stores the given operations
+func (s *Store) Put(ops ...batch.Operation) error {
+
+	b, err := newBatch(ops)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Write(b, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to store operations")
+	}
+
+	return nil
+}
+
+// PutBlock stores the operations anchored in the given block and records the block
+// as the last processed block. Both are written atomically.
+func (s *Store) PutBlock(blockNum uint64, ops []batch.Operation) error {
+
+	b, err := newBatch(ops)
+	if err != nil {
+		return err
+	}
+
+	b.Put([]byte(lastBlockKey), []byte(strconv.FormatUint(blockNum, 10)))
+
+	err = s.db.Write(b, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to store operations for block %d", blockNum)
+	}
+
+	return nil
+}
+
+// LastBlockNum returns the number of the last processed block. False is returned
+// if no block has been processed yet.
+func (s *Store) LastBlockNum() (uint64, bool, error) {
+
+	value, err := s.db.Get([]byte(lastBlockKey), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "failed to read last block number")
+	}
+
+	blockNum, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, false, errors.Wrap(err, "invalid last block number")
+	}
+
+	return blockNum, true, nil
+}
+
+// Get returns all operations for the given unique suffix in ledger order
+func (s *Store) Get(uniqueSuffix string) ([]batch.Operation, error) {
+
+	it := s.db.NewIterator(util.BytesPrefix(operationKeyPrefixFor(uniqueSuffix)), nil)
+	defer it.Release()
+
+	var ops []batch.Operation
+	for it.Next() {
+		var op batch.Operation
+		if err := json.Unmarshal(it.Value(), &op); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal operation")
+		}
+
+		ops = append(ops, op)
+	}
+
+	if err := it.Error(); err != nil {
+		return nil, errors.Wrap(err, "failed to read operations")
+	}
+
+	if len(ops) == 0 {
+		return nil, errors.New("uniqueSuffix not found in the store")
+	}
+
+	return ops, nil
+}
+
+// GetByHash returns the operation with the given hash, i.e. the multihash of its encoded payload (computed
+// with the hash algorithm of the operation) in base64url encoding. False is returned if the operation is not
+// in the store.
+func (s *Store) GetByHash(hash string) (*batch.Operation, bool, error) {
+
+	opKey, err := s.db.Get([]byte(hashKeyPrefix+hash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read operation hash")
+	}
+
+	value, err := s.db.Get(opKey, nil)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read operation")
+	}
+
+	op := &batch.Operation{}
+	if err := json.Unmarshal(value, op); err != nil {
+		return nil, false, errors.Wrap(err, "failed to unmarshal operation")
+	}
+
+	return op, true, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func newBatch(ops []batch.Operation) (*leveldb.Batch, error) {
+
+	b := new(leveldb.Batch)
+	for _, op := range ops {
+		if op.UniqueSuffix == "" {
+			return nil, errors.New("operation is missing unique suffix")
+		}
+
+		value, err := json.Marshal(op)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal operation")
+		}
+
+		b.Put(operationKey(op), value)
+
+		if hashKey, ok := operationHashKey(op); ok {
+			b.Put(hashKey, operationKey(op))
+		}
+	}
+
+	return b, nil
+}
+
+func operationKeyPrefixFor(uniqueSuffix string) []byte {
+	return []byte(operationKeyPrefix + uniqueSuffix + keySeparator)
+}
+
+// operationKey returns a key which sorts operations of the same document by
+// transaction time, transaction number and operation index
+func operationKey(op batch.Operation) []byte {
+	return append(
+		operationKeyPrefixFor(op.UniqueSuffix),
+		fmt.Sprintf("%020d%020d%010d", op.TransactionTime, op.TransactionNumber, op.OperationIndex)...,
+	)
+}
+
+// operationHashKey returns the key under which the key of the given operation is stored by operation hash.
+// False is returned if the hash cannot be computed (the operation cannot be found by hash in that case).
+func operationHashKey(op batch.Operation) ([]byte, bool) {
+
+	if op.EncodedPayload == "" {
+		return nil, false
+	}
+
+	multihash, err := docutil.ComputeMultihash(op.HashAlgorithmInMultiHashCode, []byte(op.EncodedPayload))
+	if err != nil {
+		return nil, false
+	}
+
+	return []byte(hashKeyPrefix + docutil.EncodeToString(multihash)), true
+}
@@ -0,0 +1,182 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+const uniqueSuffix = "abc"
+
+func TestNew(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	require.NotNil(t, s)
+}
+
+func TestNewError(t *testing.T) {
+	f, err := ioutil.TempFile("", "store")
+	require.Nil(t, err)
+	defer func() { require.Nil(t, os.Remove(f.Name())) }()
+
+	s, err := New(f.Name())
+	require.NotNil(t, err)
+	require.Nil(t, s)
+	require.Contains(t, err.Error(), "failed to open operation store")
+}
+
+func TestPutAndGet(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	update := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeUpdate, TransactionTime: 12}
+	create := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeCreate, TransactionTime: 2}
+	other := batch.Operation{UniqueSuffix: "xyz", Type: batch.OperationTypeCreate, TransactionTime: 3}
+
+	require.Nil(t, s.Put(update, other))
+	require.Nil(t, s.Put(create))
+
+	ops, err := s.Get(uniqueSuffix)
+	require.Nil(t, err)
+	require.Len(t, ops, 2)
+	require.Equal(t, batch.OperationTypeCreate, ops[0].Type)
+	require.Equal(t, batch.OperationTypeUpdate, ops[1].Type)
+
+	// storing the same operation twice is a no-op
+	require.Nil(t, s.Put(create))
+	ops, err = s.Get(uniqueSuffix)
+	require.Nil(t, err)
+	require.Len(t, ops, 2)
+}
+
+func TestGetByHash(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	const sha256Code = 18
+
+	create := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeCreate, TransactionTime: 2,
+		EncodedPayload: "payload", HashAlgorithmInMultiHashCode: sha256Code}
+	noPayload := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeUpdate, TransactionTime: 3,
+		HashAlgorithmInMultiHashCode: sha256Code}
+	unsupported := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeUpdate, TransactionTime: 4,
+		EncodedPayload: "payload2", HashAlgorithmInMultiHashCode: 55}
+
+	require.Nil(t, s.Put(create, noPayload, unsupported))
+
+	multihash, err := docutil.ComputeMultihash(sha256Code, []byte("payload"))
+	require.Nil(t, err)
+
+	op, ok, err := s.GetByHash(docutil.EncodeToString(multihash))
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, create, *op)
+
+	// operations whose hash cannot be computed are stored but cannot be found by hash
+	op, ok, err = s.GetByHash("unknown")
+	require.Nil(t, err)
+	require.False(t, ok)
+	require.Nil(t, op)
+
+	ops, err := s.Get(uniqueSuffix)
+	require.Nil(t, err)
+	require.Len(t, ops, 3)
+}
+
+func TestGetNotFound(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	ops, err := s.Get(uniqueSuffix)
+	require.NotNil(t, err)
+	require.Nil(t, ops)
+	require.Contains(t, err.Error(), "not found")
+}
+
+func TestPutMissingUniqueSuffix(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	err := s.Put(batch.Operation{Type: batch.OperationTypeCreate})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "missing unique suffix")
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	require.Nil(t, err)
+	defer func() { require.Nil(t, os.RemoveAll(dir)) }()
+
+	s, err := New(dir)
+	require.Nil(t, err)
+	require.Nil(t, s.Put(batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeCreate}))
+	require.Nil(t, s.Close())
+
+	s, err = New(dir)
+	require.Nil(t, err)
+	defer func() { require.Nil(t, s.Close()) }()
+
+	ops, err := s.Get(uniqueSuffix)
+	require.Nil(t, err)
+	require.Len(t, ops, 1)
+}
+
+func TestPutBlock(t *testing.T) {
+	s, cleanup := newStore(t)
+	defer cleanup()
+
+	_, ok, err := s.LastBlockNum()
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	op := batch.Operation{UniqueSuffix: uniqueSuffix, Type: batch.OperationTypeCreate, TransactionTime: 5}
+	require.Nil(t, s.PutBlock(5, []batch.Operation{op}))
+
+	blockNum, ok, err := s.LastBlockNum()
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(5), blockNum)
+
+	ops, err := s.Get(uniqueSuffix)
+	require.Nil(t, err)
+	require.Len(t, ops, 1)
+
+	// blocks without anchors still advance the last block number
+	require.Nil(t, s.PutBlock(6, nil))
+
+	blockNum, ok, err = s.LastBlockNum()
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(6), blockNum)
+
+	err = s.PutBlock(7, []batch.Operation{{Type: batch.OperationTypeCreate}})
+	require.NotNil(t, err)
+
+	blockNum, _, err = s.LastBlockNum()
+	require.Nil(t, err)
+	require.Equal(t, uint64(6), blockNum)
+}
+
+func newStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	require.Nil(t, err)
+
+	s, err := New(dir)
+	require.Nil(t, err)
+
+	return s, func() {
+		require.Nil(t, s.Close())
+		require.Nil(t, os.RemoveAll(dir))
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txn
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+// DefaultCommitTimeout is the time to wait for a transaction to be committed if no timeout is configured
+const DefaultCommitTimeout = 30 * time.Second
+
+var logger = logrus.New()
+
+// Receipt identifies the Fabric transaction in which a chaincode invocation was committed
+type Receipt struct {
+	// TxID is the ID of the Fabric transaction
+	TxID string
+	// BlockNumber is the number of the block which contains the transaction
+	// (zero if the block could not be determined)
+	BlockNumber uint64
+}
+
+// InvalidTxError is returned by Execute if the transaction was marked invalid by the committer
+type InvalidTxError struct {
+	TxID string
+	Code pb.TxValidationCode
+}
+
+func (e *InvalidTxError) Error() string {
+	return fmt.Sprintf("transaction [%s] is invalid: %s", e.TxID, e.Code)
+}
+
+type chClient interface {
+	Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error)
+	Execute(request channel.Request, options ...channel.RequestOption) (channel.Response, error)
+}
+
+type ledgerClient interface {
+	QueryBlockByTxID(txID fab.TransactionID, options ...ledger.RequestOption) (*cb.Block, error)
+	QueryInfo(options ...ledger.RequestOption) (*fab.BlockchainInfoResponse, error)
+}
+
+// Client invokes the chaincode. Transactions are executed synchronously: Execute returns once the
+// transaction was committed (or the commit timeout expired).
+type Client struct {
+	lock            sync.RWMutex
+	channelProvider context.ChannelProvider
+	channelClient   chClient
+	ledgerClient    ledgerClient
+	commitTimeout   time.Duration
+}
+
+// New returns a new transaction client which waits for the given timeout for transactions to be committed
+func New(channelProvider context.ChannelProvider, commitTimeout time.Duration) *Client {
+
+	if commitTimeout <= 0 {
+		commitTimeout = DefaultCommitTimeout
+	}
+
+	return &Client{channelProvider: channelProvider, commitTimeout: commitTimeout}
+}
+
+// Query queries the chaincode and returns the payload of the response
+func (c *Client) Query(request channel.Request) ([]byte, error) {
+
+	chc, _, err := c.getClients()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get channel client")
+	}
+
+	response, err := chc.Query(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Payload, nil
+}
+
+// Execute invokes the chaincode and waits for the transaction to be committed
+// returns the payload of the response and the receipt of the committed transaction. An error
+// is returned if the transaction was not committed or was marked invalid by the committer.
+func (c *Client) Execute(request channel.Request) ([]byte, *Receipt, error) {
+
+	chc, lc, err := c.getClients()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get channel client")
+	}
+
+	response, err := chc.Execute(request, channel.WithTimeout(fab.Execute, c.commitTimeout))
+	if err != nil {
+		// the channel client returns the validation code of an invalid transaction as an event server status
+		if s, ok := status.FromError(err); ok && s.Group == status.EventServerStatus {
+			return nil, nil, &InvalidTxError{TxID: string(response.TransactionID), Code: pb.TxValidationCode(s.Code)}
+		}
+
+		return nil, nil, err
+	}
+
+	if response.TxValidationCode != pb.TxValidationCode_VALID {
+		return nil, nil, &InvalidTxError{TxID: string(response.TransactionID), Code: response.TxValidationCode}
+	}
+
+	receipt := &Receipt{TxID: string(response.TransactionID)}
+
+	// the transaction was committed, so failing to determine the block must not fail the invocation
+	block, err := lc.QueryBlockByTxID(response.TransactionID)
+	if err != nil {
+		logger.Warnf("Failed to determine the block of transaction [%s]: %s", response.TransactionID, err)
+	} else if block.Header != nil {
+		receipt.BlockNumber = block.Header.Number
+	}
+
+	return response.Payload, receipt, nil
+}
+
+// BlockHeight queries a peer for the height of the channel (i.e. the number of the last block plus one)
+func (c *Client) BlockHeight() (uint64, error) {
+
+	_, lc, err := c.getClients()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get ledger client")
+	}
+
+	info, err := lc.QueryInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	if info == nil || info.BCI == nil {
+		return 0, errors.New("invalid blockchain info")
+	}
+
+	return info.BCI.Height, nil
+}
+
+// TransactionBlock queries a peer for the number of the block which contains the given transaction
+func (c *Client) TransactionBlock(txID string) (uint64, error) {
+
+	_, lc, err := c.getClients()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get ledger client")
+	}
+
+	block, err := lc.QueryBlockByTxID(fab.TransactionID(txID))
+	if err != nil {
+		return 0, err
+	}
+
+	if block == nil || block.Header == nil {
+		return 0, errors.Errorf("invalid block of transaction [%s]", txID)
+	}
+
+	return block.Header.Number, nil
+}
+
+func (c *Client) getClients() (chClient, ledgerClient, error) {
+
+	c.lock.RLock()
+	chc, lc := c.channelClient, c.ledgerClient
+	c.lock.RUnlock()
+
+	if chc != nil && lc != nil {
+		return chc, lc, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.channelClient == nil {
+		channelClient, err := channel.New(c.channelProvider)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create channel client")
+		}
+
+		c.channelClient = channelClient
+	}
+
+	if c.ledgerClient == nil {
+		ledgerClient, err := ledger.New(c.channelProvider)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to create ledger client")
+		}
+
+		c.ledgerClient = ledgerClient
+	}
+
+	return c.channelClient, c.ledgerClient, nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	fabMocks "github.com/hyperledger/fabric-sdk-go/pkg/fab/mocks"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	chID = "mychannel"
+	txID = "txID"
+)
+
+func TestNew(t *testing.T) {
+	c := New(channelProvider(chID), 0)
+	require.NotNil(t, c)
+	require.Equal(t, DefaultCommitTimeout, c.commitTimeout)
+
+	c = New(channelProvider(chID), time.Minute)
+	require.Equal(t, time.Minute, c.commitTimeout)
+}
+
+func TestExecute(t *testing.T) {
+	cc := &mockChannelClient{response: channel.Response{TransactionID: txID, Payload: []byte("payload")}}
+	lc := &mockLedgerClient{blockNum: 7}
+
+	c := newClient(cc, lc)
+
+	payload, receipt, err := c.Execute(channel.Request{Fcn: "fcn"})
+	require.Nil(t, err)
+	require.Equal(t, []byte("payload"), payload)
+	require.Equal(t, &Receipt{TxID: txID, BlockNumber: 7}, receipt)
+	require.Equal(t, "fcn", cc.request.Fcn)
+	require.Equal(t, fab.TransactionID(txID), lc.txID)
+
+	// the transaction was committed even though its block could not be determined
+	lc.err = errors.New("ledger error")
+
+	payload, receipt, err = c.Execute(channel.Request{Fcn: "fcn"})
+	require.Nil(t, err)
+	require.Equal(t, []byte("payload"), payload)
+	require.Equal(t, &Receipt{TxID: txID}, receipt)
+}
+
+func TestExecute_InvalidTransaction(t *testing.T) {
+	cc := &mockChannelClient{response: channel.Response{TransactionID: txID, TxValidationCode: pb.TxValidationCode_MVCC_READ_CONFLICT}}
+
+	c := newClient(cc, &mockLedgerClient{})
+
+	payload, receipt, err := c.Execute(channel.Request{})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Nil(t, receipt)
+
+	invalidTxErr, ok := err.(*InvalidTxError)
+	require.True(t, ok)
+	require.Equal(t, txID, invalidTxErr.TxID)
+	require.Equal(t, pb.TxValidationCode_MVCC_READ_CONFLICT, invalidTxErr.Code)
+	require.Contains(t, err.Error(), "transaction [txID] is invalid: MVCC_READ_CONFLICT")
+
+	// the channel client reports invalid transactions as an event server status
+	cc.response = channel.Response{TransactionID: txID}
+	cc.err = status.New(status.EventServerStatus, int32(pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE), "received invalid transaction", nil)
+
+	_, _, err = c.Execute(channel.Request{})
+	require.NotNil(t, err)
+
+	invalidTxErr, ok = err.(*InvalidTxError)
+	require.True(t, ok)
+	require.Equal(t, txID, invalidTxErr.TxID)
+	require.Equal(t, pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE, invalidTxErr.Code)
+}
+
+func TestExecute_Error(t *testing.T) {
+	testErr := errors.New("channel error")
+
+	c := newClient(&mockChannelClient{err: testErr}, &mockLedgerClient{})
+
+	payload, receipt, err := c.Execute(channel.Request{})
+	require.Equal(t, testErr, err)
+	require.Nil(t, payload)
+	require.Nil(t, receipt)
+}
+
+func TestQuery(t *testing.T) {
+	cc := &mockChannelClient{response: channel.Response{Payload: []byte("payload")}}
+
+	c := newClient(cc, &mockLedgerClient{})
+
+	payload, err := c.Query(channel.Request{Fcn: "fcn"})
+	require.Nil(t, err)
+	require.Equal(t, []byte("payload"), payload)
+	require.Equal(t, "fcn", cc.request.Fcn)
+
+	cc.err = errors.New("channel error")
+
+	payload, err = c.Query(channel.Request{Fcn: "fcn"})
+	require.Equal(t, cc.err, err)
+	require.Nil(t, payload)
+}
+
+func TestBlockHeight(t *testing.T) {
+	lc := &mockLedgerClient{height: 8}
+
+	c := newClient(&mockChannelClient{}, lc)
+
+	height, err := c.BlockHeight()
+	require.Nil(t, err)
+	require.Equal(t, uint64(8), height)
+
+	lc.err = errors.New("ledger error")
+
+	height, err = c.BlockHeight()
+	require.Equal(t, lc.err, err)
+	require.Equal(t, uint64(0), height)
+}
+
+func TestTransactionBlock(t *testing.T) {
+	lc := &mockLedgerClient{blockNum: 7}
+
+	c := newClient(&mockChannelClient{}, lc)
+
+	blockNum, err := c.TransactionBlock(txID)
+	require.Nil(t, err)
+	require.Equal(t, uint64(7), blockNum)
+	require.Equal(t, fab.TransactionID(txID), lc.txID)
+
+	lc.err = errors.New("ledger error")
+
+	_, err = c.TransactionBlock(txID)
+	require.Equal(t, lc.err, err)
+}
+
+func TestGetClientError(t *testing.T) {
+	testErr := errors.New("provider error")
+
+	c := New(channelProviderWithError(testErr), 0)
+
+	payload, receipt, err := c.Execute(channel.Request{})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Nil(t, receipt)
+	require.Contains(t, err.Error(), testErr.Error())
+
+	payload, err = c.Query(channel.Request{})
+	require.NotNil(t, err)
+	require.Nil(t, payload)
+	require.Contains(t, err.Error(), testErr.Error())
+
+	_, err = c.BlockHeight()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), testErr.Error())
+
+	_, err = c.TransactionBlock(txID)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), testErr.Error())
+}
+
+func TestGetClients(t *testing.T) {
+	c := New(channelProvider(chID), 0)
+
+	chc, lc, err := c.getClients()
+	require.Nil(t, err)
+	require.NotNil(t, chc)
+	require.NotNil(t, lc)
+
+	chc2, lc2, err := c.getClients()
+	require.Nil(t, err)
+	require.True(t, chc == chc2)
+	require.True(t, lc == lc2)
+}
+
+func newClient(cc chClient, lc ledgerClient) *Client {
+	c := New(channelProvider(chID), 0)
+	c.channelClient = cc
+	c.ledgerClient = lc
+
+	return c
+}
+
+func channelProvider(channelID string) context.ChannelProvider {
+	channelProvider := func() (context.Channel, error) {
+		return fabMocks.NewMockChannel(channelID)
+	}
+	return channelProvider
+}
+
+func channelProviderWithError(err error) context.ChannelProvider {
+	channelProvider := func() (context.Channel, error) {
+		return nil, err
+	}
+	return channelProvider
+}
+
+type mockChannelClient struct {
+	request  channel.Request
+	response channel.Response
+	err      error
+}
+
+func (m *mockChannelClient) Query(request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	m.request = request
+	return m.response, m.err
+}
+
+func (m *mockChannelClient) Execute(request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	m.request = request
+	return m.response, m.err
+}
+
+type mockLedgerClient struct {
+	txID     fab.TransactionID
+	blockNum uint64
+	height   uint64
+	err      error
+}
+
+func (m *mockLedgerClient) QueryBlockByTxID(txID fab.TransactionID, options ...ledger.RequestOption) (*cb.Block, error) {
+	m.txID = txID
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &cb.Block{Header: &cb.BlockHeader{Number: m.blockNum}}, nil
+}
+
+func (m *mockLedgerClient) QueryInfo(options ...ledger.RequestOption) (*fab.BlockchainInfoResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &fab.BlockchainInfoResponse{BCI: &cb.BlockchainInfo{Height: m.height}}, nil
+}
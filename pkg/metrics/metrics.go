@@ -0,0 +1,151 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// prefix is the prefix of the names of all metrics of the node
+	prefix = "sidetree"
+
+	// CASRead and CASWrite are the operation labels of the CAS metrics
+	CASRead  = "read"
+	CASWrite = "write"
+)
+
+var (
+	// RequestsTotal counts the API requests by route, method and status code
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prefix,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of API requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// RequestDuration observes the time to serve API requests by route and method
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: prefix,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve API requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// CASDuration observes the time to read content from (or write content to) the ledger.
+	// Reads which are served from the cache are not observed.
+	CASDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: prefix,
+		Subsystem: "cas",
+		Name:      "duration_seconds",
+		Help:      "Time to read or write CAS content on the ledger by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// CASErrors counts the CAS reads and writes which failed
+	CASErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prefix,
+		Subsystem: "cas",
+		Name:      "errors_total",
+		Help:      "Number of failed CAS reads or writes by operation.",
+	}, []string{"operation"})
+
+	// AnchorDuration observes the time to submit an anchor and wait for it to be committed (including retries)
+	AnchorDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: prefix,
+		Subsystem: "anchor",
+		Name:      "duration_seconds",
+		Help:      "Time to submit an anchor and wait for its transaction to be committed.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	})
+
+	// AnchorErrors counts the anchors which failed
+	AnchorErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prefix,
+		Subsystem: "anchor",
+		Name:      "errors_total",
+		Help:      "Number of anchors which failed.",
+	})
+
+	// BatchSize observes the number of operations of anchored batches
+	BatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: prefix,
+		Subsystem: "batch",
+		Name:      "operations",
+		Help:      "Number of operations per batch.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	// PendingOperations is the number of operations which were accepted by the batch writers
+	// of all namespaces but not yet anchored
+	PendingOperations = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: prefix,
+		Subsystem: "batch",
+		Name:      "pending_operations",
+		Help:      "Number of operations waiting to be anchored.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsTotal,
+		RequestDuration,
+		CASDuration,
+		CASErrors,
+		AnchorDuration,
+		AnchorErrors,
+		BatchSize,
+		PendingOperations,
+	)
+}
+
+// Handler returns the handler which serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveCAS records the duration of the CAS operation which started at the given time and whether it failed
+func ObserveCAS(operation string, start time.Time, err error) {
+	CASDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		CASErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveAnchor records the duration of the anchor which was submitted at the given time and whether it failed
+func ObserveAnchor(start time.Time, err error) {
+	AnchorDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		AnchorErrors.Inc()
+	}
+}
+
+// RegisterObserverHeight registers a gauge which reports the block height processed by the observer of
+// the given DID namespace (i.e. the number of the last processed block plus one). Zero is reported
+// if the height cannot be determined.
+func RegisterObserverHeight(namespace string, height func() (uint64, error)) error {
+	return prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   prefix,
+		Subsystem:   "observer",
+		Name:        "block_height",
+		Help:        "Block height processed by the observer.",
+		ConstLabels: prometheus.Labels{"namespace": namespace},
+	}, func() float64 {
+		h, err := height()
+		if err != nil {
+			return 0
+		}
+		return float64(h)
+	}))
+}
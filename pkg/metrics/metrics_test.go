@@ -0,0 +1,45 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveCAS(t *testing.T) {
+	errs := testutil.ToFloat64(CASErrors.WithLabelValues(CASRead))
+
+	ObserveCAS(CASRead, time.Now(), nil)
+	require.Equal(t, errs, testutil.ToFloat64(CASErrors.WithLabelValues(CASRead)))
+
+	ObserveCAS(CASRead, time.Now(), errors.New("read error"))
+	require.Equal(t, errs+1, testutil.ToFloat64(CASErrors.WithLabelValues(CASRead)))
+}
+
+func TestObserveAnchor(t *testing.T) {
+	errs := testutil.ToFloat64(AnchorErrors)
+
+	ObserveAnchor(time.Now(), nil)
+	require.Equal(t, errs, testutil.ToFloat64(AnchorErrors))
+
+	ObserveAnchor(time.Now(), errors.New("anchor error"))
+	require.Equal(t, errs+1, testutil.ToFloat64(AnchorErrors))
+}
+
+func TestRegisterObserverHeight(t *testing.T) {
+	height := func() (uint64, error) { return 7, nil }
+
+	require.Nil(t, RegisterObserverHeight("did:metrics:", height))
+
+	// the gauge of a namespace is only registered once
+	require.NotNil(t, RegisterObserverHeight("did:metrics:", height))
+}
@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+// anchor is a Sidetree transaction recorded on the ledger
+type anchor struct {
+	// TxNum is the position of the Fabric transaction within the block
+	TxNum uint64
+	// TxID is the ID of the Fabric transaction
+	TxID string
+	// Address is the CAS address of the anchor file
+	Address string
+}
+
+// getAnchors returns the anchors written by the given chaincode in the given block.
+// Transactions which were marked invalid by the committer are skipped.
+func getAnchors(block *cb.Block, ccID, anchorPrefix string) ([]anchor, error) {
+
+	var anchors []anchor
+	err := visitWrites(block, ccID, func(txNum uint64, txID string, w *kvrwset.KVWrite) {
+		if w.IsDelete || !strings.HasPrefix(w.Key, anchorPrefix) {
+			return
+		}
+
+		anchors = append(anchors, anchor{TxNum: txNum, TxID: txID, Address: string(w.Value)})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return anchors, nil
+}
+
+// hasWrite returns true if the given chaincode wrote the given key in a valid transaction of the given block
+func hasWrite(block *cb.Block, ccID, key string) (bool, error) {
+
+	found := false
+	err := visitWrites(block, ccID, func(txNum uint64, txID string, w *kvrwset.KVWrite) {
+		if w.Key == key {
+			found = true
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// visitWrites invokes the given visitor for each key write of the given chaincode in the given block.
+// Transactions which were marked invalid by the committer are skipped.
+func visitWrites(block *cb.Block, ccID string, visit func(txNum uint64, txID string, w *kvrwset.KVWrite)) error {
+
+	if block.Header == nil || block.Data == nil || block.Metadata == nil {
+		return errors.New("invalid block")
+	}
+
+	txFilter := getTxFilter(block)
+
+	for txNum, envBytes := range block.Data.Data {
+		if !isValid(txFilter, txNum) {
+			logger.Debugf("Skipping invalid transaction %d in block %d", txNum, block.Header.Number)
+			continue
+		}
+
+		txID, writes, err := getWrites(envBytes, ccID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read transaction %d in block %d", txNum, block.Header.Number)
+		}
+
+		for _, w := range writes {
+			visit(uint64(txNum), txID, w)
+		}
+	}
+
+	return nil
+}
+
+func getTxFilter(block *cb.Block) []byte {
+	if len(block.Metadata.Metadata) <= int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		return nil
+	}
+
+	return block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]
+}
+
+func isValid(txFilter []byte, txNum int) bool {
+	if txNum >= len(txFilter) {
+		// a committed block always has a validation code for every transaction
+		return false
+	}
+
+	return pb.TxValidationCode(txFilter[txNum]) == pb.TxValidationCode_VALID
+}
+
+// getWrites returns the transaction ID and the key writes of the given chaincode
+// for an endorser transaction. Other transaction types have no writes.
+func getWrites(envBytes []byte, ccID string) (string, []*kvrwset.KVWrite, error) {
+
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(envBytes, env); err != nil {
+		return "", nil, errors.Wrap(err, "failed to unmarshal envelope")
+	}
+
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return "", nil, errors.Wrap(err, "failed to unmarshal payload")
+	}
+
+	if payload.Header == nil {
+		return "", nil, errors.New("payload header is missing")
+	}
+
+	chdr := &cb.ChannelHeader{}
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, chdr); err != nil {
+		return "", nil, errors.Wrap(err, "failed to unmarshal channel header")
+	}
+
+	if cb.HeaderType(chdr.Type) != cb.HeaderType_ENDORSER_TRANSACTION {
+		return chdr.TxId, nil, nil
+	}
+
+	tx := &pb.Transaction{}
+	if err := proto.Unmarshal(payload.Data, tx); err != nil {
+		return "", nil, errors.Wrap(err, "failed to unmarshal transaction")
+	}
+
+	var writes []*kvrwset.KVWrite
+	for _, action := range tx.Actions {
+		w, err := getActionWrites(action, ccID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		writes = append(writes, w...)
+	}
+
+	return chdr.TxId, writes, nil
+}
+
+func getActionWrites(action *pb.TransactionAction, ccID string) ([]*kvrwset.KVWrite, error) {
+
+	ccActionPayload := &pb.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.Payload, ccActionPayload); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chaincode action payload")
+	}
+
+	if ccActionPayload.Action == nil {
+		return nil, errors.New("chaincode endorsed action is missing")
+	}
+
+	prp := &pb.ProposalResponsePayload{}
+	if err := proto.Unmarshal(ccActionPayload.Action.ProposalResponsePayload, prp); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal proposal response payload")
+	}
+
+	ccAction := &pb.ChaincodeAction{}
+	if err := proto.Unmarshal(prp.Extension, ccAction); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal chaincode action")
+	}
+
+	txRWSet := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(ccAction.Results, txRWSet); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal read-write set")
+	}
+
+	for _, nsRWSet := range txRWSet.NsRwset {
+		if nsRWSet.Namespace != ccID {
+			continue
+		}
+
+		kvRWSet := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(nsRWSet.Rwset, kvRWSet); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal key-value read-write set")
+		}
+
+		return kvRWSet.Writes, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/ledger/rwset/kvrwset"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+)
+
+const (
+	txID1 = "tx1"
+	txID2 = "tx2"
+	txID3 = "tx3"
+)
+
+func TestGetAnchors(t *testing.T) {
+	block := newBlock(7,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"addr1", "addr1"),
+		newTx(txID2, pb.TxValidationCode_MVCC_READ_CONFLICT, sidetreeTxnCC, anchorAddrPrefix+"addr2", "addr2"),
+		newTx(txID3, pb.TxValidationCode_VALID, "othercc", anchorAddrPrefix+"addr3", "addr3"),
+		newTx("tx4", pb.TxValidationCode_VALID, sidetreeTxnCC, "otherkey", "value"),
+		newTx("tx5", pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"addr5", "addr5"),
+	)
+
+	anchors, err := getAnchors(block, sidetreeTxnCC, anchorAddrPrefix)
+	require.Nil(t, err)
+	require.Len(t, anchors, 2)
+
+	require.Equal(t, anchor{TxNum: 0, TxID: txID1, Address: "addr1"}, anchors[0])
+	require.Equal(t, anchor{TxNum: 4, TxID: "tx5", Address: "addr5"}, anchors[1])
+}
+
+func TestGetAnchorsConfigTx(t *testing.T) {
+	block := newBlock(0)
+	block.Data.Data = append(block.Data.Data, marshal(&cb.Envelope{
+		Payload: marshal(&cb.Payload{
+			Header: &cb.Header{ChannelHeader: marshal(&cb.ChannelHeader{Type: int32(cb.HeaderType_CONFIG)})},
+		}),
+	}))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{byte(pb.TxValidationCode_VALID)}
+
+	anchors, err := getAnchors(block, sidetreeTxnCC, anchorAddrPrefix)
+	require.Nil(t, err)
+	require.Empty(t, anchors)
+}
+
+func TestGetAnchorsInvalidBlock(t *testing.T) {
+	anchors, err := getAnchors(&cb.Block{}, sidetreeTxnCC, anchorAddrPrefix)
+	require.NotNil(t, err)
+	require.Nil(t, anchors)
+	require.Contains(t, err.Error(), "invalid block")
+
+	block := newBlock(3)
+	block.Data.Data = append(block.Data.Data, []byte("invalid envelope"))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{byte(pb.TxValidationCode_VALID)}
+
+	anchors, err = getAnchors(block, sidetreeTxnCC, anchorAddrPrefix)
+	require.NotNil(t, err)
+	require.Nil(t, anchors)
+	require.Contains(t, err.Error(), "failed to read transaction 0 in block 3")
+}
+
+func TestGetAnchorsMissingTxFilter(t *testing.T) {
+	block := newBlock(1, newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"addr1", "addr1"))
+	block.Metadata.Metadata = nil
+
+	anchors, err := getAnchors(block, sidetreeTxnCC, anchorAddrPrefix)
+	require.Nil(t, err)
+	require.Empty(t, anchors)
+}
+
+func TestHasWrite(t *testing.T) {
+	block := newBlock(2,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"addr1", "addr1"),
+		newTx(txID2, pb.TxValidationCode_MVCC_READ_CONFLICT, sidetreeTxnCC, protocolKey, "{}"),
+	)
+
+	found, err := hasWrite(block, sidetreeTxnCC, protocolKey)
+	require.Nil(t, err)
+	require.False(t, found)
+
+	block = newBlock(3,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, protocolKey, "{}"),
+	)
+
+	found, err = hasWrite(block, sidetreeTxnCC, protocolKey)
+	require.Nil(t, err)
+	require.True(t, found)
+
+	found, err = hasWrite(&cb.Block{}, sidetreeTxnCC, protocolKey)
+	require.NotNil(t, err)
+	require.False(t, found)
+}
+
+type tx struct {
+	envelope []byte
+	code     pb.TxValidationCode
+}
+
+func newBlock(blockNum uint64, txs ...tx) *cb.Block {
+	block := &cb.Block{
+		Header:   &cb.BlockHeader{Number: blockNum},
+		Data:     &cb.BlockData{},
+		Metadata: &cb.BlockMetadata{Metadata: make([][]byte, len(cb.BlockMetadataIndex_name))},
+	}
+
+	txFilter := make([]byte, len(txs))
+	for i, t := range txs {
+		block.Data.Data = append(block.Data.Data, t.envelope)
+		txFilter[i] = byte(t.code)
+	}
+
+	block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER] = txFilter
+
+	return block
+}
+
+func newTx(txID string, code pb.TxValidationCode, ccID, key, value string) tx {
+	kvRWSet := &kvrwset.KVRWSet{
+		Writes: []*kvrwset.KVWrite{{Key: key, Value: []byte(value)}},
+	}
+
+	txRWSet := &rwset.TxReadWriteSet{
+		NsRwset: []*rwset.NsReadWriteSet{{Namespace: ccID, Rwset: marshal(kvRWSet)}},
+	}
+
+	prp := &pb.ProposalResponsePayload{
+		Extension: marshal(&pb.ChaincodeAction{Results: marshal(txRWSet)}),
+	}
+
+	ccActionPayload := &pb.ChaincodeActionPayload{
+		Action: &pb.ChaincodeEndorsedAction{ProposalResponsePayload: marshal(prp)},
+	}
+
+	transaction := &pb.Transaction{
+		Actions: []*pb.TransactionAction{{Payload: marshal(ccActionPayload)}},
+	}
+
+	chdr := &cb.ChannelHeader{Type: int32(cb.HeaderType_ENDORSER_TRANSACTION), TxId: txID}
+
+	payload := &cb.Payload{
+		Header: &cb.Header{ChannelHeader: marshal(chdr)},
+		Data:   marshal(transaction),
+	}
+
+	return tx{envelope: marshal(&cb.Envelope{Payload: marshal(payload)}), code: code}
+}
+
+func marshal(msg proto.Message) []byte {
+	bytes, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+
+	return bytes
+}
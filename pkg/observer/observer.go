@@ -0,0 +1,394 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/event"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/events/deliverclient/seek"
+	cb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/common"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+)
+
+const (
+	// protocolKey is the key of the protocol version table in the state of the Sidetree transaction chaincode
+	protocolKey = "protocol"
+
+	// initialRetryInterval is the time to wait before processing a block again after the first failure.
+	// The interval is doubled after each failure up to maxRetryInterval.
+	initialRetryInterval = time.Second
+	maxRetryInterval     = time.Minute
+)
+
+var logger = logrus.New()
+
+type casReader interface {
+	Read(address string) ([]byte, error)
+}
+
+type operationStore interface {
+	PutBlock(blockNum uint64, ops []batch.Operation) error
+	LastBlockNum() (uint64, bool, error)
+}
+
+type protocolClient interface {
+	Get(blockchainTime uint64) (protocolApi.Protocol, error)
+	Refresh() error
+}
+
+type eventService interface {
+	RegisterBlockEvent(filter ...fab.BlockFilter) (fab.Registration, <-chan *fab.BlockEvent, error)
+	Unregister(reg fab.Registration)
+}
+
+// Config identifies the anchors of a Sidetree deployment on the channel
+type Config struct {
+	// ChaincodeID is the ID of the Sidetree transaction chaincode
+	ChaincodeID string
+	// AnchorPrefix is the key prefix under which the chaincode records anchor addresses
+	AnchorPrefix string
+}
+
+// Observer follows the blocks committed to the Sidetree channel and feeds the operations
+// of anchored Sidetree transactions into the operation store in ledger order
+type Observer struct {
+	lock            sync.Mutex
+	channelProvider context.ChannelProvider
+	cfg             Config
+	cas             casReader
+	store           operationStore
+	protocolClient  protocolClient
+	newEventService func(fromBlock uint64) (eventService, error)
+	done            chan struct{}
+	wg              sync.WaitGroup
+	running         int32
+
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	// blockProcessed is invoked after each attempt to process a block (used in tests)
+	blockProcessed func(blockNum uint64, err error)
+}
+
+// New returns a new observer for the anchors identified by the given configuration
+func New(channelProvider context.ChannelProvider, cfg Config, cas casReader, store operationStore, pc protocolClient) *Observer {
+
+	o := &Observer{
+		channelProvider: channelProvider,
+		cfg:             cfg,
+		cas:             cas,
+		store:           store,
+		protocolClient:  pc,
+
+		retryInterval:    initialRetryInterval,
+		maxRetryInterval: maxRetryInterval,
+		blockProcessed:   func(uint64, error) {},
+	}
+
+	o.newEventService = o.newBlockEventService
+
+	return o
+}
+
+// Start starts listening for blocks, resuming after the last block that was processed
+func (o *Observer) Start() error {
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.done != nil {
+		return errors.New("observer already started")
+	}
+
+	fromBlock, err := o.nextBlockNum()
+	if err != nil {
+		return err
+	}
+
+	es, err := o.newEventService(fromBlock)
+	if err != nil {
+		return errors.Wrap(err, "failed to create event service")
+	}
+
+	reg, eventch, err := es.RegisterBlockEvent()
+	if err != nil {
+		return errors.Wrap(err, "failed to register for block events")
+	}
+
+	logger.Infof("Starting observer from block %d", fromBlock)
+
+	o.done = make(chan struct{})
+	o.wg.Add(1)
+	atomic.StoreInt32(&o.running, 1)
+
+	go o.listen(es, reg, eventch, o.done)
+
+	return nil
+}
+
+// Stop stops the observer and waits for the block being processed (if any) to complete
+func (o *Observer) Stop() {
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.done == nil {
+		return
+	}
+
+	close(o.done)
+	o.wg.Wait()
+	o.done = nil
+
+	logger.Info("Observer stopped")
+}
+
+// Running returns true if the observer is listening for blocks. False is returned if the observer
+// was not started, was stopped or the block event channel was closed.
+func (o *Observer) Running() bool {
+	return atomic.LoadInt32(&o.running) == 1
+}
+
+// BlockHeight returns the height of the chain of processed blocks (i.e. the number of the last processed block plus one)
+func (o *Observer) BlockHeight() (uint64, error) {
+	return o.nextBlockNum()
+}
+
+// Lag returns the number of blocks of a channel with the given height which were not processed yet
+func (o *Observer) Lag(height uint64) (uint64, error) {
+
+	processed, err := o.BlockHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	if processed >= height {
+		return 0, nil
+	}
+
+	return height - processed, nil
+}
+
+func (o *Observer) nextBlockNum() (uint64, error) {
+
+	lastBlockNum, ok, err := o.store.LastBlockNum()
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to determine the last processed block")
+	}
+
+	if !ok {
+		return 0, nil
+	}
+
+	return lastBlockNum + 1, nil
+}
+
+func (o *Observer) newBlockEventService(fromBlock uint64) (eventService, error) {
+	return event.New(
+		o.channelProvider,
+		event.WithBlockEvents(),
+		event.WithSeekType(seek.FromBlock),
+		event.WithBlockNum(fromBlock),
+	)
+}
+
+func (o *Observer) listen(es eventService, reg fab.Registration, eventch <-chan *fab.BlockEvent, done chan struct{}) {
+
+	defer o.wg.Done()
+	defer es.Unregister(reg)
+	defer atomic.StoreInt32(&o.running, 0)
+
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-eventch:
+			if !ok {
+				logger.Warn("Block event channel closed")
+				return
+			}
+
+			if !o.processBlockWithRetry(e.Block, done) {
+				return
+			}
+		}
+	}
+}
+
+// processBlockWithRetry processes the given block until it succeeds or the observer is stopped, since skipping
+// the block would lose operations. Invalid anchors and operations are skipped by processBlock so the errors it
+// returns are transient (e.g. content which was not replicated yet or a store failure) and the block is retried
+// with an increasing interval. False is returned if the observer was stopped.
+func (o *Observer) processBlockWithRetry(block *cb.Block, done chan struct{}) bool {
+
+	interval := o.retryInterval
+
+	for {
+		err := o.processBlock(block)
+		o.blockProcessed(blockNumber(block), err)
+		if err == nil {
+			return true
+		}
+
+		if errors.Cause(err) == cas.ErrContentNotFound {
+			// private data is disseminated to the peers asynchronously
+			logger.Warnf("Content of block is not available yet: %s. Retrying in %s", err, interval)
+		} else {
+			logger.Errorf("Failed to process block: %s. Retrying in %s", err, interval)
+		}
+
+		select {
+		case <-done:
+			return false
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > o.maxRetryInterval {
+			interval = o.maxRetryInterval
+		}
+	}
+}
+
+// processBlock stores the operations of the anchors in the given block
+func (o *Observer) processBlock(block *cb.Block) error {
+
+	if block == nil || block.Header == nil {
+		return errors.New("invalid block")
+	}
+
+	blockNum := block.Header.Number
+
+	lastBlockNum, ok, err := o.store.LastBlockNum()
+	if err != nil {
+		return errors.WithMessage(err, "failed to determine the last processed block")
+	}
+
+	if ok && blockNum <= lastBlockNum {
+		logger.Debugf("Block %d was already processed", blockNum)
+		return nil
+	}
+
+	anchors, err := getAnchors(block, o.cfg.ChaincodeID, o.cfg.AnchorPrefix)
+	if err != nil {
+		return err
+	}
+
+	err = o.refreshProtocol(block)
+	if err != nil {
+		return err
+	}
+
+	ops, err := o.getBlockOperations(blockNum, anchors)
+	if err != nil {
+		return err
+	}
+
+	err = o.store.PutBlock(blockNum, ops)
+	if err != nil {
+		return err
+	}
+
+	logger.Debugf("Processed block %d: %d anchors, %d operations", blockNum, len(anchors), len(ops))
+
+	return nil
+}
+
+// refreshProtocol reloads the protocol versions if the protocol version table was updated in the given block.
+// The protocol versions are reloaded before the operations of the block are processed so that a version
+// which starts at this block is applied to it.
+func (o *Observer) refreshProtocol(block *cb.Block) error {
+
+	updated, err := hasWrite(block, o.cfg.ChaincodeID, protocolKey)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return nil
+	}
+
+	logger.Infof("Protocol versions were updated in block %d", block.Header.Number)
+
+	return errors.WithMessage(o.protocolClient.Refresh(), "failed to refresh protocol versions")
+}
+
+// getBlockOperations returns the operations of the given anchors. Anchors whose content was read and is
+// invalid (i.e. a malformed anchor or batch file or content which fails the integrity check) are logged and
+// skipped since processing the block again would fail again. Any other error is returned so that the block
+// is processed again.
+func (o *Observer) getBlockOperations(blockNum uint64, anchors []anchor) ([]batch.Operation, error) {
+
+	if len(anchors) == 0 {
+		return nil, nil
+	}
+
+	// the operations are processed with the protocol version in force when they were anchored
+	p, err := o.protocolClient.Get(blockNum)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []batch.Operation
+	for _, a := range anchors {
+		logger.Debugf("Processing anchor [%s] of transaction [%s] in block %d", a.Address, a.TxID, blockNum)
+
+		info := txnInfo{
+			transactionTime:   blockNum,
+			transactionNumber: a.TxNum,
+			hashAlgorithm:     p.HashAlgorithmInMultiHashCode,
+		}
+
+		var anchorOps []batch.Operation
+		anchorOps, err = o.getOperations(a.Address, info)
+		if err != nil {
+			if !isInvalidContent(err) {
+				return nil, err
+			}
+
+			logger.Errorf("Skipping invalid anchor [%s] of transaction [%s] in block %d: %s", a.Address, a.TxID, blockNum, err)
+			continue
+		}
+
+		ops = append(ops, anchorOps...)
+	}
+
+	return ops, nil
+}
+
+// isInvalidContent returns true if the given error proves that the content of an anchor is invalid, i.e. the
+// content does not match its address or cannot be parsed. Any other error (including unknown errors) may not
+// occur when the anchor is processed again.
+func isInvalidContent(err error) bool {
+
+	switch errors.Cause(err).(type) {
+	case *cas.IntegrityError, invalidContentError:
+		return true
+	default:
+		return false
+	}
+}
+
+func blockNumber(block *cb.Block) uint64 {
+
+	if block == nil || block.Header == nil {
+		return 0
+	}
+
+	return block.Header.Number
+}
@@ -0,0 +1,731 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	protocolApi "github.com/trustbloc/sidetree-core-go/pkg/api/protocol"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	fabMocks "github.com/hyperledger/fabric-sdk-go/pkg/fab/mocks"
+	pb "github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/protos/peer"
+
+	"github.com/trustbloc/sidetree-fabric/pkg/context/cas"
+)
+
+const (
+	chID        = "mychannel"
+	sha256Code  = 18
+	anchorAddr  = "anchorAddr"
+	batchAddr   = "batchAddr"
+	didSuffix   = "EiDOQXC2GnoVyHwIRbjhLx_cNc6vmZaS04SZjZdlLLAPRg=="
+	waitTimeout = 5 * time.Second
+
+	sidetreeTxnCC    = "sidetreetxn_cc"
+	anchorAddrPrefix = "sidetreetxn_"
+)
+
+var testConfig = Config{ChaincodeID: sidetreeTxnCC, AnchorPrefix: anchorAddrPrefix}
+
+func TestObserver(t *testing.T) {
+	cas := newMockCAS()
+	cas.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	cas.put(batchAddr, &batchFile{Operations: []string{createOp(t), updateOp(t)}})
+
+	s := newMockStore()
+	es := newMockEventService()
+
+	o := newObserver(cas, s, es)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	require.Equal(t, uint64(0), es.fromBlock)
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(0)}
+	es.eventch <- &fab.BlockEvent{Block: newBlock(1,
+		newTx(txID1, pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE, sidetreeTxnCC, anchorAddrPrefix+"other", "other"),
+		newTx(txID2, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+
+	waitForBlock(t, processed, 1)
+
+	ops := s.getOps(1)
+	require.Len(t, ops, 2)
+
+	require.Equal(t, batch.OperationTypeCreate, ops[0].Type)
+	require.NotEmpty(t, ops[0].UniqueSuffix)
+	require.Equal(t, uint64(1), ops[0].TransactionTime)
+	require.Equal(t, uint64(1), ops[0].TransactionNumber)
+	require.Equal(t, uint(0), ops[0].OperationIndex)
+
+	require.Equal(t, batch.OperationTypeUpdate, ops[1].Type)
+	require.Equal(t, didSuffix, ops[1].UniqueSuffix)
+	require.Equal(t, uint(1), ops[1].OperationNumber)
+	require.Equal(t, uint(1), ops[1].OperationIndex)
+}
+
+func TestObserverConfig(t *testing.T) {
+	cas := newMockCAS()
+	cas.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	cas.put(batchAddr, &batchFile{Operations: []string{createOp(t), updateOp(t)}})
+
+	s := newMockStore()
+
+	o := New(channelProvider(chID), Config{ChaincodeID: "othercc", AnchorPrefix: "other_"}, cas, s, newMockProtocolClient())
+
+	// only the anchors of the configured deployment are processed
+	err := o.processBlock(newBlock(0,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+		newTx(txID2, pb.TxValidationCode_VALID, "othercc", anchorAddrPrefix+anchorAddr, anchorAddr),
+		newTx(txID3, pb.TxValidationCode_VALID, "othercc", "other_"+anchorAddr, anchorAddr),
+	))
+	require.Nil(t, err)
+
+	ops := s.getOps(0)
+	require.Len(t, ops, 2)
+	require.Equal(t, uint64(2), ops[0].TransactionNumber)
+}
+
+func TestObserverResume(t *testing.T) {
+	s := newMockStore()
+	require.Nil(t, s.PutBlock(9, nil))
+
+	es := newMockEventService()
+
+	o := newObserver(newMockCAS(), s, es)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	require.Equal(t, uint64(10), es.fromBlock)
+
+	// a block which was already processed is ignored
+	es.eventch <- &fab.BlockEvent{Block: newBlock(9,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+	es.eventch <- &fab.BlockEvent{Block: newBlock(10)}
+
+	waitForBlock(t, processed, 10)
+	require.Empty(t, s.getOps(9))
+}
+
+func TestObserverRetry(t *testing.T) {
+	c := newMockCAS()
+	s := newMockStore()
+	es := newMockEventService()
+
+	o := newObserver(c, s, es)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(0,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+
+	// the anchor file is not available so the block is not processed
+	err := waitForAttempt(t, processed, 0)
+	require.Equal(t, cas.ErrContentNotFound, errors.Cause(err))
+
+	_, ok, err := s.LastBlockNum()
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	// the peers cannot be reached
+	c.setErr(anchorAddr, errors.Wrap(cas.ErrTransport, "peer unavailable"))
+	waitForErr(t, processed, 0, cas.ErrTransport)
+
+	// the content becomes available
+	c.setErr(anchorAddr, nil)
+	c.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	c.put(batchAddr, &batchFile{Operations: []string{createOp(t)}})
+
+	waitForBlock(t, processed, 0)
+	require.Len(t, s.getOps(0), 1)
+}
+
+func TestObserverMissingContent(t *testing.T) {
+	c := newMockCAS()
+	c.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	c.put(batchAddr, &batchFile{Operations: []string{createOp(t)}})
+
+	s := newMockStore()
+	es := newMockEventService()
+
+	o := newObserver(c, s, es)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(0,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"missing", "missing"),
+		newTx(txID2, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+
+	// the block is retried for as long as the content is missing
+	for i := 0; i < 5; i++ {
+		err := waitForAttempt(t, processed, 0)
+		require.Equal(t, cas.ErrContentNotFound, errors.Cause(err))
+	}
+
+	// unknown errors are retried as well
+	errUnknown := errors.New("unknown error")
+	c.setErr("missing", errUnknown)
+	waitForErr(t, processed, 0, errUnknown)
+
+	_, ok, err := s.LastBlockNum()
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	c.setErr("missing", nil)
+	c.put("missing", &anchorFile{BatchFileHash: batchAddr})
+
+	waitForBlock(t, processed, 0)
+	require.Len(t, s.getOps(0), 2)
+}
+
+func TestObserverInvalidAnchor(t *testing.T) {
+	c := newMockCAS()
+	c.content["malformed"] = []byte("{")
+	c.put("nobatch", &anchorFile{})
+	c.setErr("tampered", &cas.IntegrityError{Address: "tampered", ComputedAddress: "other"})
+	c.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	c.put(batchAddr, &batchFile{Operations: []string{"!invalid!", encodeOp(t, "recover", ""), createOp(t), updateOp(t)}})
+
+	s := newMockStore()
+	es := newMockEventService()
+
+	o := newObserver(c, s, es)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(0,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"malformed", "malformed"),
+		newTx(txID2, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"nobatch", "nobatch"),
+		newTx(txID3, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"tampered", "tampered"),
+	)}
+	es.eventch <- &fab.BlockEvent{Block: newBlock(1,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+"malformed", "malformed"),
+		newTx(txID2, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+
+	// the invalid anchors are skipped without retrying the blocks
+	require.Equal(t, blockResult{blockNum: 0}, <-processed)
+	require.Empty(t, s.getOps(0))
+
+	require.Equal(t, blockResult{blockNum: 1}, <-processed)
+
+	// the invalid operations of the valid anchor are skipped
+	ops := s.getOps(1)
+	require.Len(t, ops, 2)
+	require.Equal(t, batch.OperationTypeCreate, ops[0].Type)
+	require.Equal(t, uint(2), ops[0].OperationIndex)
+	require.Equal(t, batch.OperationTypeUpdate, ops[1].Type)
+	require.Equal(t, uint(3), ops[1].OperationIndex)
+}
+
+func TestObserverProtocolVersion(t *testing.T) {
+	cas := newMockCAS()
+	cas.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+	cas.put(batchAddr, &batchFile{Operations: []string{createOp(t)}})
+
+	s := newMockStore()
+	es := newMockEventService()
+	pc := newMockProtocolClient()
+
+	o := newObserverWithProtocol(cas, s, es, pc)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(5,
+		newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, anchorAddrPrefix+anchorAddr, anchorAddr),
+	)}
+
+	waitForBlock(t, processed, 5)
+	require.Len(t, s.getOps(5), 1)
+
+	// the protocol version is looked up by the block number of the anchor
+	require.Equal(t, []uint64{5}, pc.getRequests())
+}
+
+func TestObserverProtocolRefresh(t *testing.T) {
+	s := newMockStore()
+	es := newMockEventService()
+	pc := newMockProtocolClient()
+
+	o := newObserverWithProtocol(newMockCAS(), s, es, pc)
+	processed := processedBlocks(o)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	es.eventch <- &fab.BlockEvent{Block: newBlock(0,
+		newTx(txID1, pb.TxValidationCode_VALID, "othercc", protocolKey, "{}"),
+	)}
+	es.eventch <- &fab.BlockEvent{Block: newBlock(1,
+		newTx(txID2, pb.TxValidationCode_VALID, sidetreeTxnCC, protocolKey, "{}"),
+	)}
+
+	waitForBlock(t, processed, 1)
+	require.Equal(t, 1, pc.getRefreshed())
+}
+
+func TestProcessBlockProtocolRefreshError(t *testing.T) {
+	pc := newMockProtocolClient()
+	pc.refreshErr = errors.New("refresh error")
+
+	s := newMockStore()
+	o := newObserverWithProtocol(newMockCAS(), s, newMockEventService(), pc)
+
+	err := o.processBlock(newBlock(0, newTx(txID1, pb.TxValidationCode_VALID, sidetreeTxnCC, protocolKey, "{}")))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to refresh protocol versions: refresh error")
+
+	// the block is processed again
+	_, ok, err := s.LastBlockNum()
+	require.Nil(t, err)
+	require.False(t, ok)
+}
+
+func TestGetBlockOperationsProtocolError(t *testing.T) {
+	pc := newMockProtocolClient()
+	pc.err = errors.New("protocol error")
+
+	o := newObserverWithProtocol(newMockCAS(), newMockStore(), newMockEventService(), pc)
+
+	ops, err := o.getBlockOperations(1, nil)
+	require.Nil(t, err)
+	require.Empty(t, ops)
+
+	ops, err = o.getBlockOperations(1, []anchor{{TxID: txID1, Address: anchorAddr}})
+	require.NotNil(t, err)
+	require.Nil(t, ops)
+	require.Contains(t, err.Error(), "protocol error")
+}
+
+func TestObserverRunning(t *testing.T) {
+	es := newMockEventService()
+
+	o := newObserver(newMockCAS(), newMockStore(), es)
+	require.False(t, o.Running())
+
+	require.Nil(t, o.Start())
+	require.True(t, o.Running())
+
+	o.Stop()
+	require.False(t, o.Running())
+
+	// the observer stops listening if the block event channel is closed
+	es = newMockEventService()
+	o = newObserver(newMockCAS(), newMockStore(), es)
+	require.Nil(t, o.Start())
+	defer o.Stop()
+
+	close(es.eventch)
+
+	// wait for the listener to exit
+	o.wg.Wait()
+	require.False(t, o.Running())
+}
+
+func TestObserverLag(t *testing.T) {
+	s := newMockStore()
+	o := newObserver(newMockCAS(), s, newMockEventService())
+
+	// no blocks were processed
+	lag, err := o.Lag(5)
+	require.Nil(t, err)
+	require.Equal(t, uint64(5), lag)
+
+	require.Nil(t, s.PutBlock(2, nil))
+
+	height, err := o.BlockHeight()
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), height)
+
+	lag, err = o.Lag(5)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), lag)
+
+	lag, err = o.Lag(3)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), lag)
+
+	s.err = errors.New("store error")
+
+	_, err = o.Lag(5)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "store error")
+}
+
+func TestObserverStartErrors(t *testing.T) {
+	t.Run("already started", func(t *testing.T) {
+		o := newObserver(newMockCAS(), newMockStore(), newMockEventService())
+		require.Nil(t, o.Start())
+		defer o.Stop()
+
+		err := o.Start()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "already started")
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		s := newMockStore()
+		s.err = errors.New("store error")
+
+		err := newObserver(newMockCAS(), s, newMockEventService()).Start()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "store error")
+	})
+
+	t.Run("event service error", func(t *testing.T) {
+		o := New(channelProvider(chID), testConfig, newMockCAS(), newMockStore(), newMockProtocolClient())
+		o.newEventService = func(uint64) (eventService, error) { return nil, errors.New("event service error") }
+
+		err := o.Start()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "event service error")
+	})
+
+	t.Run("registration error", func(t *testing.T) {
+		es := newMockEventService()
+		es.err = errors.New("registration error")
+
+		err := newObserver(newMockCAS(), newMockStore(), es).Start()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "registration error")
+	})
+}
+
+func TestGetOperationsErrors(t *testing.T) {
+	c := newMockCAS()
+	o := newObserver(c, newMockStore(), newMockEventService())
+
+	_, err := o.getOperations(anchorAddr, txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to read anchor file")
+	require.False(t, isInvalidContent(err))
+
+	c.put(anchorAddr, &anchorFile{})
+
+	_, err = o.getOperations(anchorAddr, txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "does not contain the batch file hash")
+	require.True(t, isInvalidContent(err))
+
+	c.put(anchorAddr, &anchorFile{BatchFileHash: batchAddr})
+
+	_, err = o.getOperations(anchorAddr, txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to read batch file")
+
+	c.content[batchAddr] = []byte("[]")
+	_, err = o.getOperations(anchorAddr, txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to unmarshal content")
+	require.True(t, isInvalidContent(err))
+
+	// invalid operations are skipped
+	c.put(batchAddr, &batchFile{Operations: []string{"!invalid!", encodeOp(t, "recover", "")}})
+	ops, err := o.getOperations(anchorAddr, txnInfo{hashAlgorithm: sha256Code})
+	require.Nil(t, err)
+	require.Empty(t, ops)
+
+	c.put(batchAddr, &batchFile{Operations: []string{encodeOp(t, "create", "payload")}})
+	ops, err = o.getOperations(anchorAddr, txnInfo{hashAlgorithm: 55})
+	require.Nil(t, err)
+	require.Empty(t, ops)
+}
+
+func TestDecodeOperationErrors(t *testing.T) {
+	_, err := decodeOperation("!invalid!", txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to decode operation")
+
+	_, err = decodeOperation(encodeOp(t, "recover", ""), txnInfo{hashAlgorithm: sha256Code})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "not supported")
+
+	_, err = decodeOperation(encodeOp(t, "create", "payload"), txnInfo{hashAlgorithm: 55})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to compute unique suffix")
+}
+
+func newObserver(cas casReader, s operationStore, es *mockEventService) *Observer {
+	return newObserverWithProtocol(cas, s, es, newMockProtocolClient())
+}
+
+func newObserverWithProtocol(cas casReader, s operationStore, es *mockEventService, pc protocolClient) *Observer {
+	o := New(channelProvider(chID), testConfig, cas, s, pc)
+	o.retryInterval = time.Millisecond
+	o.maxRetryInterval = time.Millisecond
+	o.newEventService = func(fromBlock uint64) (eventService, error) {
+		es.fromBlock = fromBlock
+		return es, nil
+	}
+
+	return o
+}
+
+// blockResult is the result of an attempt to process a block
+type blockResult struct {
+	blockNum uint64
+	err      error
+}
+
+// processedBlocks returns a channel which receives the result of each attempt of the given observer to process
+// a block. It must be called before the observer is started.
+func processedBlocks(o *Observer) <-chan blockResult {
+	processed := make(chan blockResult, 100)
+	o.blockProcessed = func(blockNum uint64, err error) {
+		processed <- blockResult{blockNum: blockNum, err: err}
+	}
+
+	return processed
+}
+
+// waitForAttempt waits for the next attempt to process the given block and returns its error
+func waitForAttempt(t *testing.T, processed <-chan blockResult, blockNum uint64) error {
+	select {
+	case result := <-processed:
+		require.Equal(t, blockNum, result.blockNum)
+		return result.err
+	case <-time.After(waitTimeout):
+		t.Fatalf("timed out waiting for block %d", blockNum)
+		return nil
+	}
+}
+
+// waitForErr waits until an attempt to process the given block fails with the given cause. Attempts which
+// failed before the cause was set up are skipped.
+func waitForErr(t *testing.T, processed <-chan blockResult, blockNum uint64, cause error) {
+	for {
+		if errors.Cause(waitForAttempt(t, processed, blockNum)) == cause {
+			return
+		}
+	}
+}
+
+// waitForBlock waits until the given block was processed successfully
+func waitForBlock(t *testing.T, processed <-chan blockResult, blockNum uint64) {
+	for {
+		select {
+		case result := <-processed:
+			if result.err == nil && result.blockNum == blockNum {
+				return
+			}
+		case <-time.After(waitTimeout):
+			t.Fatalf("timed out waiting for block %d", blockNum)
+		}
+	}
+}
+
+func createOp(t *testing.T) string {
+	doc := docutil.EncodeToString([]byte(`{"@context":"https://w3id.org/did/v1","publicKey":[]}`))
+	return encodeOp(t, "create", doc)
+}
+
+func updateOp(t *testing.T) string {
+	payload, err := json.Marshal(map[string]interface{}{
+		"didUniqueSuffix":       didSuffix,
+		"operationNumber":       1,
+		"previousOperationHash": didSuffix,
+		"patch":                 []map[string]interface{}{{"op": "remove", "path": "/publicKey/0"}},
+	})
+	require.Nil(t, err)
+
+	return encodeOp(t, "update", docutil.EncodeToString(payload))
+}
+
+func encodeOp(t *testing.T, opType, payload string) string {
+	op, err := json.Marshal(map[string]interface{}{
+		"header":    map[string]string{"operation": opType, "kid": "#key1"},
+		"payload":   payload,
+		"signature": "signature",
+	})
+	require.Nil(t, err)
+
+	return docutil.EncodeToString(op)
+}
+
+func channelProvider(channelID string) context.ChannelProvider {
+	return func() (context.Channel, error) {
+		return fabMocks.NewMockChannel(channelID)
+	}
+}
+
+type mockCAS struct {
+	mutex   sync.RWMutex
+	content map[string][]byte
+	errs    map[string]error
+}
+
+func newMockCAS() *mockCAS {
+	return &mockCAS{content: make(map[string][]byte), errs: make(map[string]error)}
+}
+
+func (m *mockCAS) put(address string, v interface{}) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.content[address] = bytes
+}
+
+func (m *mockCAS) setErr(address string, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.errs[address] = err
+}
+
+func (m *mockCAS) Read(address string) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if err := m.errs[address]; err != nil {
+		return nil, err
+	}
+
+	content, ok := m.content[address]
+	if !ok {
+		return nil, errors.Wrapf(cas.ErrContentNotFound, "content not found for address [%s]", address)
+	}
+
+	return content, nil
+}
+
+type mockStore struct {
+	mutex        sync.RWMutex
+	ops          map[uint64][]batch.Operation
+	lastBlockNum *uint64
+	err          error
+}
+
+func newMockStore() *mockStore {
+	return &mockStore{ops: make(map[uint64][]batch.Operation)}
+}
+
+func (m *mockStore) PutBlock(blockNum uint64, ops []batch.Operation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.ops[blockNum] = ops
+	m.lastBlockNum = &blockNum
+
+	return nil
+}
+
+func (m *mockStore) getOps(blockNum uint64) []batch.Operation {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.ops[blockNum]
+}
+
+func (m *mockStore) LastBlockNum() (uint64, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.err != nil {
+		return 0, false, m.err
+	}
+
+	if m.lastBlockNum == nil {
+		return 0, false, nil
+	}
+
+	return *m.lastBlockNum, true, nil
+}
+
+type mockProtocolClient struct {
+	mutex      sync.Mutex
+	requests   []uint64
+	refreshed  int
+	err        error
+	refreshErr error
+}
+
+func newMockProtocolClient() *mockProtocolClient {
+	return &mockProtocolClient{}
+}
+
+func (m *mockProtocolClient) Get(blockchainTime uint64) (protocolApi.Protocol, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests = append(m.requests, blockchainTime)
+
+	if m.err != nil {
+		return protocolApi.Protocol{}, m.err
+	}
+
+	return protocolApi.Protocol{
+		HashAlgorithmInMultiHashCode: sha256Code,
+		MaxOperationsPerBatch:        100,
+		MaxOperationByteSize:         2000,
+	}, nil
+}
+
+func (m *mockProtocolClient) Refresh() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.refreshed++
+
+	return m.refreshErr
+}
+
+func (m *mockProtocolClient) getRefreshed() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.refreshed
+}
+
+func (m *mockProtocolClient) getRequests() []uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.requests
+}
+
+type mockEventService struct {
+	eventch   chan *fab.BlockEvent
+	fromBlock uint64
+	err       error
+}
+
+func newMockEventService() *mockEventService {
+	return &mockEventService{eventch: make(chan *fab.BlockEvent, 10)}
+}
+
+func (m *mockEventService) RegisterBlockEvent(filter ...fab.BlockFilter) (fab.Registration, <-chan *fab.BlockEvent, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	return "registration", m.eventch, nil
+}
+
+func (m *mockEventService) Unregister(reg fab.Registration) {
+}
@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package observer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"github.com/trustbloc/sidetree-core-go/pkg/api/batch"
+	"github.com/trustbloc/sidetree-core-go/pkg/docutil"
+)
+
+// anchorFile defines the schema of an anchor file
+type anchorFile struct {
+	// BatchFileHash is the CAS address of the batch file
+	BatchFileHash string `json:"batchFileHash"`
+}
+
+// batchFile defines the schema of a batch file
+type batchFile struct {
+	// Operations contains the encoded operations of the batch
+	Operations []string `json:"operations"`
+}
+
+// operationRequest is the operation request as it was submitted to the node
+type operationRequest struct {
+	Header struct {
+		Operation batch.OperationType `json:"operation"`
+		KeyID     string              `json:"kid"`
+	} `json:"header"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// updatePayload is the decoded payload of update and delete operations
+type updatePayload struct {
+	DidUniqueSuffix       string          `json:"didUniqueSuffix"`
+	OperationNumber       uint            `json:"operationNumber"`
+	PreviousOperationHash string          `json:"previousOperationHash"`
+	Patch                 jsonpatch.Patch `json:"patch"`
+}
+
+// invalidContentError is returned if content was read from CAS but does not conform to the schema of the file
+type invalidContentError struct {
+	msg string
+}
+
+func (e invalidContentError) Error() string {
+	return e.msg
+}
+
+// txnInfo holds the ledger position and protocol parameters of a Sidetree transaction
+type txnInfo struct {
+	transactionTime   uint64
+	transactionNumber uint64
+	hashAlgorithm     uint
+}
+
+// getOperations reads the anchor and batch files of the given anchor from CAS and returns the decoded
+// operations. Operations which cannot be decoded are logged and skipped.
+func (o *Observer) getOperations(anchorAddress string, info txnInfo) ([]batch.Operation, error) {
+
+	af := &anchorFile{}
+	if err := o.readFile(anchorAddress, af); err != nil {
+		return nil, errors.Wrapf(err, "failed to read anchor file [%s]", anchorAddress)
+	}
+
+	if af.BatchFileHash == "" {
+		return nil, invalidContentError{msg: fmt.Sprintf("anchor file [%s] does not contain the batch file hash", anchorAddress)}
+	}
+
+	bf := &batchFile{}
+	if err := o.readFile(af.BatchFileHash, bf); err != nil {
+		return nil, errors.Wrapf(err, "failed to read batch file [%s]", af.BatchFileHash)
+	}
+
+	ops := make([]batch.Operation, 0, len(bf.Operations))
+	for index, encodedOp := range bf.Operations {
+		op, err := decodeOperation(encodedOp, info)
+		if err != nil {
+			logger.Errorf("Skipping invalid operation %d of batch file [%s]: %s", index, af.BatchFileHash, err)
+			continue
+		}
+
+		op.OperationIndex = uint(index)
+		ops = append(ops, *op)
+	}
+
+	return ops, nil
+}
+
+func (o *Observer) readFile(address string, v interface{}) error {
+
+	content, err := o.cas.Read(address)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return invalidContentError{msg: fmt.Sprintf("failed to unmarshal content: %s", err)}
+	}
+
+	return nil
+}
+
+func decodeOperation(encodedOp string, info txnInfo) (*batch.Operation, error) {
+
+	opBytes, err := docutil.DecodeString(encodedOp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode operation")
+	}
+
+	req := &operationRequest{}
+	err = json.Unmarshal(opBytes, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal operation")
+	}
+
+	op := &batch.Operation{
+		Type:                         req.Header.Operation,
+		OperationBuffer:              opBytes,
+		EncodedPayload:               req.Payload,
+		Signature:                    req.Signature,
+		SigningKeyID:                 req.Header.KeyID,
+		HashAlgorithmInMultiHashCode: info.hashAlgorithm,
+		TransactionTime:              info.transactionTime,
+		TransactionNumber:            info.transactionNumber,
+	}
+
+	switch op.Type {
+	case batch.OperationTypeCreate:
+		op.UniqueSuffix, err = uniqueSuffix(req.Payload, info.hashAlgorithm)
+	case batch.OperationTypeUpdate, batch.OperationTypeDelete:
+		err = applyUpdatePayload(op, req.Payload)
+	default:
+		err = errors.Errorf("operation type [%s] not supported", op.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// uniqueSuffix calculates the unique suffix of a document from the encoded payload of its create operation
+func uniqueSuffix(encodedPayload string, hashAlgorithm uint) (string, error) {
+
+	multihash, err := docutil.ComputeMultihash(hashAlgorithm, []byte(encodedPayload))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to compute unique suffix")
+	}
+
+	return docutil.EncodeToString(multihash), nil
+}
+
+func applyUpdatePayload(op *batch.Operation, encodedPayload string) error {
+
+	payloadBytes, err := docutil.DecodeString(encodedPayload)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode payload")
+	}
+
+	payload := &updatePayload{}
+	err = json.Unmarshal(payloadBytes, payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal payload")
+	}
+
+	op.UniqueSuffix = payload.DidUniqueSuffix
+	op.OperationNumber = payload.OperationNumber
+	op.PreviousOperationHash = payload.PreviousOperationHash
+	op.Patch = payload.Patch
+
+	return nil
+}